@@ -0,0 +1,381 @@
+package rr
+
+import (
+    "context"
+    "sync"
+    "time"
+)
+
+var ErrExceptionCircuitOpen = newBuiltinException("Circuit breaker is open", "CIRCUIT_OPEN", 503, CanonicalUnavailable)
+
+type CircuitState int32
+
+const (
+    CircuitClosed CircuitState = iota
+    CircuitOpen
+    CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+    switch s {
+    case CircuitClosed:
+        return "closed"
+    case CircuitOpen:
+        return "open"
+    case CircuitHalfOpen:
+        return "half-open"
+    }
+    return "unknown"
+}
+
+// 熔断器当前统计, Requests/Successes/Failures 为滚动窗口内的数量
+type CircuitCounts struct {
+    Requests             int
+    Successes            int
+    Failures             int
+    ConsecutiveFailures  int
+    ConsecutiveSuccesses int
+}
+
+// 熔断器
+// 关闭状态下统计失败, 达到阈值后打开; 打开状态直接返回 ErrExceptionCircuitOpen;
+// 冷却时间过后进入半开状态放行少量探测请求, 全部成功则关闭, 任一失败重新打开
+type CircuitBreaker interface {
+    // 执行 fn 并记录结果, 熔断时不会调用 fn
+    Execute(fn func() error) error
+    // 同 Execute, ctx 结束时直接返回
+    Do(ctx context.Context, fn func(ctx context.Context) error) error
+    // 包装为受熔断保护的函数, 可直接交给 Async / RetryWithOptions 等使用
+    Wrap(fn func(ctx context.Context) error) func(ctx context.Context) error
+    // 手动申请放行, 成功时需调用 done 上报结果
+    Allow() (done func(err error), err error)
+    State() CircuitState
+    Counts() CircuitCounts
+    // 重置为关闭状态并清空统计
+    Reset()
+}
+
+type circuitBucket struct {
+    successes int
+    failures  int
+}
+
+type circuitBreaker struct {
+    mu sync.Mutex
+
+    consecutiveFailures int
+    failureRatio        float64
+    minRequests         int
+    window              time.Duration
+    coolDown            time.Duration
+    halfOpenRequests    int
+    onStateChange       func(from, to CircuitState)
+    isFailure           func(err error) bool
+    now                 func() time.Time
+
+    state      CircuitState
+    generation uint64
+    openedAt   time.Time
+    buckets    []circuitBucket
+    bucketAt   time.Time
+    cursor     int
+    counts     CircuitCounts
+    inFlight   int
+}
+
+type CircuitBreakerOption func(*circuitBreaker)
+
+// 连续失败 n 次后打开, <= 0 关闭该条件, 默认 5
+func CircuitConsecutiveFailures(n int) CircuitBreakerOption {
+    return func(r *circuitBreaker) {
+        r.consecutiveFailures = n
+    }
+}
+
+// 窗口内请求数不少于 minRequests 且失败率达到 ratio 时打开, ratio <= 0 关闭该条件(默认)
+func CircuitFailureRatio(ratio float64, minRequests int) CircuitBreakerOption {
+    return func(r *circuitBreaker) {
+        r.failureRatio = ratio
+        r.minRequests = minRequests
+    }
+}
+
+// 滚动窗口长度与分桶数, 默认 10s / 10 个桶
+func CircuitWindow(window time.Duration, buckets int) CircuitBreakerOption {
+    return func(r *circuitBreaker) {
+        if window > 0 {
+            r.window = window
+        }
+        if buckets > 0 {
+            r.buckets = make([]circuitBucket, buckets)
+        }
+    }
+}
+
+// 打开后的冷却时间, 默认 30s
+func CircuitCoolDown(d time.Duration) CircuitBreakerOption {
+    return func(r *circuitBreaker) {
+        r.coolDown = d
+    }
+}
+
+// 半开状态允许的探测请求数, 全部成功后关闭, 默认 1
+func CircuitHalfOpenRequests(n int) CircuitBreakerOption {
+    return func(r *circuitBreaker) {
+        if n > 0 {
+            r.halfOpenRequests = n
+        }
+    }
+}
+
+// 状态变化回调, 在锁外同步调用
+func CircuitOnStateChange(f func(from, to CircuitState)) CircuitBreakerOption {
+    return func(r *circuitBreaker) {
+        r.onStateChange = f
+    }
+}
+
+// 判断错误是否计为失败, 默认所有非 nil 错误
+func CircuitIsFailure(f func(err error) bool) CircuitBreakerOption {
+    return func(r *circuitBreaker) {
+        if f != nil {
+            r.isFailure = f
+        }
+    }
+}
+
+func NewCircuitBreaker(opts ...CircuitBreakerOption) CircuitBreaker {
+    r := &circuitBreaker{
+        consecutiveFailures: 5,
+        window:              10 * time.Second,
+        coolDown:            30 * time.Second,
+        halfOpenRequests:    1,
+        buckets:             make([]circuitBucket, 10),
+        isFailure: func(err error) bool {
+            return err != nil
+        },
+        now: time.Now,
+    }
+    for _, opt := range opts {
+        opt(r)
+    }
+    r.bucketAt = r.now()
+    return r
+}
+
+func (r *circuitBreaker) Execute(fn func() error) error {
+    done, err := r.Allow()
+    if err != nil {
+        return err
+    }
+    defer func() {
+        if p := recover(); p != nil {
+            done(ErrExceptionServer)
+            panic(p)
+        }
+    }()
+    err = fn()
+    done(err)
+    return err
+}
+
+func (r *circuitBreaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+    return r.Execute(func() error {
+        return fn(ctx)
+    })
+}
+
+func (r *circuitBreaker) Wrap(fn func(ctx context.Context) error) func(ctx context.Context) error {
+    return func(ctx context.Context) error {
+        return r.Do(ctx, fn)
+    }
+}
+
+func (r *circuitBreaker) Allow() (func(err error), error) {
+    r.mu.Lock()
+    now := r.now()
+    from := r.state
+    r.refreshState(now)
+    switch {
+    case r.state == CircuitOpen,
+        r.state == CircuitHalfOpen && r.inFlight >= r.halfOpenRequests:
+        to := r.state
+        r.mu.Unlock()
+        r.notify(from, to)
+        return nil, ErrExceptionCircuitOpen
+    }
+    r.inFlight++
+    generation := r.generation
+    to := r.state
+    r.mu.Unlock()
+    r.notify(from, to)
+
+    var once sync.Once
+    return func(err error) {
+        once.Do(func() {
+            r.report(generation, err)
+        })
+    }, nil
+}
+
+func (r *circuitBreaker) report(generation uint64, err error) {
+    r.mu.Lock()
+    now := r.now()
+    from := r.state
+    r.refreshState(now)
+    if generation != r.generation {
+        // 状态已切换, 旧请求的结果不再计入
+        to := r.state
+        r.mu.Unlock()
+        r.notify(from, to)
+        return
+    }
+    r.inFlight--
+    r.advance(now)
+    if r.isFailure(err) {
+        r.onFailure(now)
+    } else {
+        r.onSuccess(now)
+    }
+    to := r.state
+    r.mu.Unlock()
+    r.notify(from, to)
+}
+
+func (r *circuitBreaker) onSuccess(now time.Time) {
+    r.buckets[r.cursor].successes++
+    r.counts.ConsecutiveSuccesses++
+    r.counts.ConsecutiveFailures = 0
+    if r.state == CircuitHalfOpen && r.counts.ConsecutiveSuccesses >= r.halfOpenRequests {
+        r.setState(CircuitClosed, now)
+    }
+}
+
+func (r *circuitBreaker) onFailure(now time.Time) {
+    r.buckets[r.cursor].failures++
+    r.counts.ConsecutiveFailures++
+    r.counts.ConsecutiveSuccesses = 0
+    if r.state == CircuitHalfOpen {
+        r.setState(CircuitOpen, now)
+        return
+    }
+    if r.consecutiveFailures > 0 && r.counts.ConsecutiveFailures >= r.consecutiveFailures {
+        r.setState(CircuitOpen, now)
+        return
+    }
+    if r.failureRatio > 0 {
+        c := r.windowCounts()
+        if c.Requests >= r.minRequests && float64(c.Failures)/float64(c.Requests) >= r.failureRatio {
+            r.setState(CircuitOpen, now)
+        }
+    }
+}
+
+// 打开状态冷却结束后进入半开
+func (r *circuitBreaker) refreshState(now time.Time) {
+    if r.state == CircuitOpen && now.Sub(r.openedAt) >= r.coolDown {
+        r.setState(CircuitHalfOpen, now)
+    }
+}
+
+func (r *circuitBreaker) setState(state CircuitState, now time.Time) {
+    if r.state == state {
+        return
+    }
+    r.state = state
+    r.generation++
+    r.inFlight = 0
+    r.counts = CircuitCounts{}
+    r.clearBuckets(now)
+    if state == CircuitOpen {
+        r.openedAt = now
+    }
+}
+
+// 按时间推进滚动窗口
+func (r *circuitBreaker) advance(now time.Time) {
+    size := r.window / time.Duration(len(r.buckets))
+    if size <= 0 {
+        size = 1
+    }
+    elapsed := int(now.Sub(r.bucketAt) / size)
+    if elapsed <= 0 {
+        return
+    }
+    if elapsed >= len(r.buckets) {
+        r.clearBuckets(now)
+        return
+    }
+    for i := 0; i < elapsed; i++ {
+        r.cursor = (r.cursor + 1) % len(r.buckets)
+        r.buckets[r.cursor] = circuitBucket{}
+    }
+    r.bucketAt = r.bucketAt.Add(time.Duration(elapsed) * size)
+}
+
+func (r *circuitBreaker) clearBuckets(now time.Time) {
+    for i := range r.buckets {
+        r.buckets[i] = circuitBucket{}
+    }
+    r.cursor = 0
+    r.bucketAt = now
+}
+
+func (r *circuitBreaker) windowCounts() CircuitCounts {
+    c := r.counts
+    c.Successes, c.Failures = 0, 0
+    for _, b := range r.buckets {
+        c.Successes += b.successes
+        c.Failures += b.failures
+    }
+    c.Requests = c.Successes + c.Failures
+    return c
+}
+
+func (r *circuitBreaker) notify(from, to CircuitState) {
+    if from != to && r.onStateChange != nil {
+        r.onStateChange(from, to)
+    }
+}
+
+func (r *circuitBreaker) State() CircuitState {
+    r.mu.Lock()
+    from := r.state
+    r.refreshState(r.now())
+    to := r.state
+    r.mu.Unlock()
+    r.notify(from, to)
+    return to
+}
+
+func (r *circuitBreaker) Counts() CircuitCounts {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.advance(r.now())
+    return r.windowCounts()
+}
+
+func (r *circuitBreaker) Reset() {
+    r.mu.Lock()
+    from := r.state
+    now := r.now()
+    r.setState(CircuitClosed, now)
+    r.counts = CircuitCounts{}
+    r.clearBuckets(now)
+    r.mu.Unlock()
+    r.notify(from, CircuitClosed)
+}
+
+// 带返回值的熔断执行, 可配合 AsyncResult 使用
+func CircuitExecute[T any](ctx context.Context, cb CircuitBreaker, fn func(ctx context.Context) (T, error)) (T, error) {
+    var v T
+    err := cb.Do(ctx, func(ctx context.Context) error {
+        var err error
+        v, err = fn(ctx)
+        return err
+    })
+    return v, err
+}
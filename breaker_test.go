@@ -0,0 +1,117 @@
+package rr
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "testing"
+    "time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+    now := time.Now()
+    var transitions []string
+    cb := NewCircuitBreaker(
+        CircuitConsecutiveFailures(3),
+        CircuitCoolDown(time.Second),
+        CircuitOnStateChange(func(from, to CircuitState) {
+            transitions = append(transitions, from.String()+"->"+to.String())
+        }),
+    )
+    cb.(*circuitBreaker).now = func() time.Time { return now }
+
+    fail := errors.New("fail")
+    for i := 0; i < 3; i++ {
+        if err := cb.Execute(func() error { return fail }); err != fail {
+            t.Fatalf("第%d次 err = %v", i+1, err)
+        }
+    }
+    if cb.State() != CircuitOpen {
+        t.Fatalf("state = %v, 期望 open", cb.State())
+    }
+
+    called := false
+    err := cb.Execute(func() error {
+        called = true
+        return nil
+    })
+    if called || !ErrExceptionCircuitOpen.Is(err) {
+        t.Fatalf("打开状态不应调用 fn, err = %v", err)
+    }
+
+    now = now.Add(time.Second)
+    if cb.State() != CircuitHalfOpen {
+        t.Fatalf("state = %v, 期望 half-open", cb.State())
+    }
+    if err := cb.Execute(func() error { return nil }); err != nil {
+        t.Fatalf("探测请求 err = %v", err)
+    }
+    if cb.State() != CircuitClosed {
+        t.Fatalf("state = %v, 期望 closed", cb.State())
+    }
+    want := []string{"closed->open", "open->half-open", "half-open->closed"}
+    if len(transitions) != len(want) {
+        t.Fatalf("transitions = %v", transitions)
+    }
+    for i := range want {
+        if transitions[i] != want[i] {
+            t.Errorf("transitions = %v, 期望 %v", transitions, want)
+        }
+    }
+}
+
+func TestCircuitBreakerFailureRatio(t *testing.T) {
+    now := time.Now()
+    cb := NewCircuitBreaker(CircuitConsecutiveFailures(0), CircuitFailureRatio(0.5, 4), CircuitWindow(time.Second, 10))
+    cb.(*circuitBreaker).now = func() time.Time { return now }
+
+    fail := errors.New("fail")
+    results := []error{nil, fail, nil}
+    for _, e := range results {
+        _ = cb.Execute(func() error { return e })
+    }
+    if cb.State() != CircuitClosed {
+        t.Fatalf("请求数不足时不应打开")
+    }
+    // 窗口滚动后旧统计失效
+    now = now.Add(2 * time.Second)
+    _ = cb.Execute(func() error { return fail })
+    if c := cb.Counts(); c.Requests != 1 || cb.State() != CircuitClosed {
+        t.Fatalf("counts = %+v state = %v", c, cb.State())
+    }
+    for i := 0; i < 3; i++ {
+        _ = cb.Execute(func() error { return fail })
+    }
+    if cb.State() != CircuitOpen {
+        t.Fatalf("state = %v, 期望 open", cb.State())
+    }
+}
+
+func TestCircuitBreakerCompose(t *testing.T) {
+    cb := NewCircuitBreaker(CircuitConsecutiveFailures(2), CircuitCoolDown(time.Hour))
+    calls := 0
+    var mu sync.Mutex
+    fn := cb.Wrap(func(ctx context.Context) error {
+        mu.Lock()
+        calls++
+        mu.Unlock()
+        return ErrExceptionNetwork
+    })
+    err := RetryWithOptions(context.Background(), fn, RetryAttempts(10), RetryBackoff(BackoffConstant(0)))
+    if !errors.Is(err, ErrExceptionCircuitOpen) || calls != 2 {
+        t.Fatalf("err=%v calls=%d", err, calls)
+    }
+
+    tasks := make([]AsyncTask, 5)
+    for i := range tasks {
+        tasks[i] = Async(context.Background(), fn)
+    }
+    for _, task := range tasks {
+        if err := task.Get(); !errors.Is(err, ErrExceptionCircuitOpen) {
+            t.Errorf("err = %v", err)
+        }
+    }
+    if calls != 2 {
+        t.Errorf("calls = %d, 熔断后不应再调用", calls)
+    }
+}
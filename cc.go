@@ -1,38 +1,349 @@
 package rr
 
-import "sync"
+import (
+    "container/list"
+    "context"
+    "sync"
+)
 
 type CC interface {
     Add()
     Done()
     Wait()
+    //  以 weight 的权重添加一项, ctx 已结束或结束前未获得名额时返回 ctx.Err(); 需以相同的 weight 调用 DoneN
+    AddN(ctx context.Context, weight int) error
+    //  释放 weight 个名额
+    DoneN(weight int)
+    //  按优先级添加, priority 越大越先放行, 同优先级先来先得
+    //  队首等待者名额不足时其后的等待者也不会被放行, 保证大权重请求不会饿死
+    AddWithPriority(ctx context.Context, weight, priority int) error
+    //  调整并发上限, 立即生效; 调小时不影响已在执行的项目, 新项目需等待占用数降到上限以下
+    SetLimit(n int)
+    //  当前已占用的权重
+    Current() int
+    //  当前并发上限
+    Limit() int
+    //  等待中的数量
+    Waiting() int
 }
 type cc struct {
-    rate  chan int
-    group *sync.WaitGroup
+    mu      sync.Mutex
+    limit   int
+    cur     int
+    waiters list.List
+    group   sync.WaitGroup
 }
 
-// 并行任务控制
+//  等待者, 获得名额时 ready 被关闭
+type ccWaiter struct {
+    weight   int
+    priority int
+    ready    chan struct{}
+}
+
+// 并行任务控制, concurrency <= 0 时所有 Add 都会阻塞直到 SetLimit 调大上限
 func NewCC(concurrency int) CC {
     r := new(cc)
-    r.rate = make(chan int, concurrency)
-    r.group = new(sync.WaitGroup)
+    r.limit = max(concurrency, 0)
     return r
 }
 
 //  添加一项
 func (r *cc) Add() {
-    r.rate <- 1
-    r.group.Add(1)
+    r.AddWithPriority(context.Background(), 1, 0)
 }
 
 //  完成一项
 func (r *cc) Done() {
-    r.group.Done()
-    <-r.rate
+    r.DoneN(1)
 }
 
 //  等待所有项目完成
 func (r *cc) Wait() {
     r.group.Wait()
 }
+
+func (r *cc) AddN(ctx context.Context, weight int) error {
+    return r.AddWithPriority(ctx, weight, 0)
+}
+
+func (r *cc) DoneN(weight int) {
+    if weight <= 0 {
+        return
+    }
+    r.mu.Lock()
+    r.cur -= weight
+    r.notify()
+    r.mu.Unlock()
+    r.group.Add(-weight)
+}
+
+//  weight 超过上限的请求会在没有其它占用时单独放行
+func (r *cc) AddWithPriority(ctx context.Context, weight, priority int) error {
+    if weight < 0 {
+        return ErrExceptionInvalidArgs.WithT("CC: negative weight")
+    }
+    if weight == 0 {
+        return nil
+    }
+    // ctx 已结束时即使有空闲名额也不放行
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+    r.mu.Lock()
+    if r.waiters.Len() == 0 && r.fits(weight) {
+        r.grant(weight)
+        r.mu.Unlock()
+        return nil
+    }
+    w := &ccWaiter{weight: weight, priority: priority, ready: make(chan struct{})}
+    elem := r.enqueue(w)
+    // 插到队首时可能立即可以放行
+    r.notify()
+    r.mu.Unlock()
+
+    select {
+    case <-w.ready:
+        return nil
+    case <-ctx.Done():
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    select {
+    case <-w.ready:
+        // 取消的同时已获得名额, 视为成功
+        return nil
+    default:
+    }
+    r.waiters.Remove(elem)
+    // 队首离开后其后的等待者可能已能获得名额
+    r.notify()
+    return ctx.Err()
+}
+
+func (r *cc) SetLimit(n int) {
+    r.mu.Lock()
+    r.limit = max(n, 0)
+    r.notify()
+    r.mu.Unlock()
+}
+
+func (r *cc) Current() int {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.cur
+}
+
+func (r *cc) Limit() int {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.limit
+}
+
+func (r *cc) Waiting() int {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.waiters.Len()
+}
+
+//  在 ctx 结束前获取一个并发名额, 成功后需调用 Done 释放
+func (r *cc) acquire(ctx context.Context) error {
+    return r.AddWithPriority(ctx, 1, 0)
+}
+
+//  非阻塞获取一个并发名额
+func (r *cc) tryAcquire() bool {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if r.waiters.Len() == 0 && r.fits(1) {
+        r.grant(1)
+        return true
+    }
+    return false
+}
+
+//  调用方需持有锁
+func (r *cc) fits(weight int) bool {
+    return r.cur+weight <= r.limit || (r.cur == 0 && r.limit > 0)
+}
+
+//  调用方需持有锁
+func (r *cc) grant(weight int) {
+    r.cur += weight
+    r.group.Add(weight)
+}
+
+//  插到最后一个优先级不低于 w 的等待者之后, 调用方需持有锁
+func (r *cc) enqueue(w *ccWaiter) *list.Element {
+    for e := r.waiters.Back(); e != nil; e = e.Prev() {
+        if e.Value.(*ccWaiter).priority >= w.priority {
+            return r.waiters.InsertAfter(w, e)
+        }
+    }
+    return r.waiters.PushFront(w)
+}
+
+//  按顺序唤醒等待者直到队首名额不足, 调用方需持有锁
+func (r *cc) notify() {
+    for {
+        front := r.waiters.Front()
+        if front == nil {
+            return
+        }
+        w := front.Value.(*ccWaiter)
+        if !r.fits(w.weight) {
+            return
+        }
+        r.waiters.Remove(front)
+        r.grant(w.weight)
+        close(w.ready)
+    }
+}
+
+//  以 concurrency 的并发度执行所有 callable, 等待全部完成后返回聚合后的 *Errors 或 nil
+//  concurrency <= 0 时不限制并发; callable 的 panic 会被转换为错误
+func CCRun(concurrency int, callables ...func() error) error {
+    var errs Errors
+    if concurrency <= 0 {
+        concurrency = max(len(callables), 1)
+    }
+    c := NewCC(concurrency)
+    for _, f := range callables {
+        c.Add()
+        go func(f func() error) {
+            defer c.Done()
+            errs.Append(callSafe(f))
+        }(f)
+    }
+    c.Wait()
+    return errs.ErrorOrNil()
+}
+
+//  带并发限制与错误收集的任务组, 用法类似 errgroup
+type CCGroup interface {
+    //  阻塞等待并发名额后启动 fn; ctx 或组 ctx 先结束时不启动 fn, 并把该错误记为组错误
+    Go(ctx context.Context, fn func(ctx context.Context) error)
+    //  无空闲名额或组已结束时立即返回 false
+    TryGo(ctx context.Context, fn func(ctx context.Context) error) bool
+    //  等待所有任务完成, 返回首个错误; 收集模式下返回聚合后的 *Errors
+    Wait() error
+    //  组 ctx, 默认模式下首个错误出现时被取消
+    Context() context.Context
+}
+
+type ccGroup struct {
+    cc         *cc
+    ctx        context.Context
+    cancel     context.CancelCauseFunc
+    collectAll bool
+    errOnce    sync.Once
+    err        error
+    errs       Errors
+}
+
+type CCGroupOption func(*ccGroup)
+
+//  收集全部错误, 不因单个错误取消其余任务
+func CCGroupCollectAll() CCGroupOption {
+    return func(r *ccGroup) {
+        r.collectAll = true
+    }
+}
+
+//  组 ctx 的父 ctx, 默认 context.Background()
+func CCGroupContext(ctx context.Context) CCGroupOption {
+    return func(r *ccGroup) {
+        r.ctx = ctx
+    }
+}
+
+//  以 concurrency 为并发上限的任务组
+func NewCCGroup(concurrency int, opts ...CCGroupOption) CCGroup {
+    r := &ccGroup{
+        cc:  NewCC(concurrency).(*cc),
+        ctx: context.Background(),
+    }
+    for _, opt := range opts {
+        opt(r)
+    }
+    r.ctx, r.cancel = context.WithCancelCause(r.ctx)
+    return r
+}
+
+func (r *ccGroup) Go(ctx context.Context, fn func(ctx context.Context) error) {
+    // 组 ctx 经 AfterFunc 异步传递到合并后的 ctx, 先直接检查两者
+    if err := ctx.Err(); err != nil {
+        r.record(err)
+        return
+    }
+    if r.ctx.Err() != nil {
+        r.record(context.Cause(r.ctx))
+        return
+    }
+    ctx, cancel := r.merge(ctx)
+    if err := r.cc.acquire(ctx); err != nil {
+        cancel()
+        r.record(err)
+        return
+    }
+    go r.run(ctx, cancel, fn)
+}
+
+func (r *ccGroup) TryGo(ctx context.Context, fn func(ctx context.Context) error) bool {
+    if ctx.Err() != nil || r.ctx.Err() != nil {
+        return false
+    }
+    if !r.cc.tryAcquire() {
+        return false
+    }
+    ctx, cancel := r.merge(ctx)
+    go r.run(ctx, cancel, fn)
+    return true
+}
+
+func (r *ccGroup) run(ctx context.Context, cancel context.CancelFunc, fn func(ctx context.Context) error) {
+    defer r.cc.Done()
+    defer cancel()
+    r.record(callSafe(func() error {
+        return fn(ctx)
+    }))
+}
+
+//  合并调用方 ctx 与组 ctx, 任一结束时返回的 ctx 都会结束
+func (r *ccGroup) merge(ctx context.Context) (context.Context, context.CancelFunc) {
+    ctx, cancel := context.WithCancelCause(ctx)
+    stop := context.AfterFunc(r.ctx, func() {
+        cancel(context.Cause(r.ctx))
+    })
+    return ctx, func() {
+        stop()
+        cancel(nil)
+    }
+}
+
+func (r *ccGroup) record(err error) {
+    if err == nil {
+        return
+    }
+    if r.collectAll {
+        r.errs.Append(err)
+        return
+    }
+    r.errOnce.Do(func() {
+        r.err = err
+        r.cancel(err)
+    })
+}
+
+func (r *ccGroup) Wait() error {
+    r.cc.Wait()
+    r.cancel(nil)
+    if r.collectAll {
+        return r.errs.ErrorOrNil()
+    }
+    return r.err
+}
+
+func (r *ccGroup) Context() context.Context {
+    return r.ctx
+}
@@ -0,0 +1,262 @@
+package rr
+
+import (
+    "context"
+    "errors"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+func TestCCGroup(t *testing.T) {
+    g := NewCCGroup(2)
+    var running, peak atomic.Int32
+    for i := 0; i < 6; i++ {
+        g.Go(context.Background(), func(ctx context.Context) error {
+            n := running.Add(1)
+            for {
+                p := peak.Load()
+                if n <= p || peak.CompareAndSwap(p, n) {
+                    break
+                }
+            }
+            time.Sleep(5 * time.Millisecond)
+            running.Add(-1)
+            return nil
+        })
+    }
+    if err := g.Wait(); err != nil || peak.Load() > 2 {
+        t.Fatalf("err=%v peak=%d", err, peak.Load())
+    }
+
+    fail := errors.New("fail")
+    g = NewCCGroup(2)
+    g.Go(context.Background(), func(ctx context.Context) error {
+        return fail
+    })
+    g.Go(context.Background(), func(ctx context.Context) error {
+        <-ctx.Done()
+        if !errors.Is(context.Cause(ctx), fail) {
+            t.Errorf("cause = %v", context.Cause(ctx))
+        }
+        return ctx.Err()
+    })
+    if err := g.Wait(); err != fail {
+        t.Errorf("应返回首个错误, err = %v", err)
+    }
+
+    // 首个错误之后 Go 不再启动 fn, 即使有空闲名额
+    g = NewCCGroup(2)
+    g.Go(context.Background(), func(ctx context.Context) error {
+        return fail
+    })
+    <-g.Context().Done()
+    var started atomic.Bool
+    g.Go(context.Background(), func(ctx context.Context) error {
+        started.Store(true)
+        return nil
+    })
+    if err := g.Wait(); err != fail || started.Load() {
+        t.Errorf("出错后不应再启动任务, err=%v started=%v", err, started.Load())
+    }
+
+    // ctx 已结束时 Go 不启动 fn
+    g = NewCCGroup(2, CCGroupCollectAll())
+    done, cancel := context.WithCancel(context.Background())
+    cancel()
+    g.Go(done, func(ctx context.Context) error {
+        started.Store(true)
+        return nil
+    })
+    if err := g.Wait(); !errors.Is(err, context.Canceled) || started.Load() {
+        t.Errorf("ctx 已结束时不应启动任务, err=%v started=%v", err, started.Load())
+    }
+
+    g = NewCCGroup(1)
+    g.Go(context.Background(), func(ctx context.Context) error {
+        panic("boom")
+    })
+    if err := g.Wait(); !errors.Is(err, ErrExceptionPanic) {
+        t.Errorf("panic 应转换为错误, err = %v", err)
+    }
+}
+
+func TestCCGroupCollectAll(t *testing.T) {
+    g := NewCCGroup(2, CCGroupCollectAll())
+    e1, e2 := errors.New("e1"), errors.New("e2")
+    var ok atomic.Bool
+    g.Go(context.Background(), func(ctx context.Context) error { return e1 })
+    g.Go(context.Background(), func(ctx context.Context) error { return e2 })
+    g.Go(context.Background(), func(ctx context.Context) error {
+        ok.Store(true)
+        return nil
+    })
+    err := g.Wait()
+    var errs *Errors
+    if !errors.As(err, &errs) || errs.Len() != 2 || !errors.Is(err, e1) || !errors.Is(err, e2) || !ok.Load() {
+        t.Errorf("err = %v", err)
+    }
+}
+
+func TestCCGroupAdmission(t *testing.T) {
+    g := NewCCGroup(1)
+    release := make(chan struct{})
+    if !g.TryGo(context.Background(), func(ctx context.Context) error {
+        <-release
+        return nil
+    }) {
+        t.Fatal("应有空闲名额")
+    }
+    if g.TryGo(context.Background(), func(ctx context.Context) error { return nil }) {
+        t.Error("名额已满时 TryGo 应返回 false")
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    defer cancel()
+    called := false
+    g.Go(ctx, func(ctx context.Context) error {
+        called = true
+        return nil
+    })
+    close(release)
+    if err := g.Wait(); !errors.Is(err, context.DeadlineExceeded) || called {
+        t.Errorf("等待名额超时应记为组错误, err=%v called=%v", err, called)
+    }
+    if g.TryGo(context.Background(), func(ctx context.Context) error { return nil }) {
+        t.Error("组结束后 TryGo 应返回 false")
+    }
+}
+
+func TestCCSetLimit(t *testing.T) {
+    c := NewCC(2)
+    c.Add()
+    c.Add()
+    if c.Current() != 2 || c.Limit() != 2 {
+        t.Fatalf("current=%d limit=%d", c.Current(), c.Limit())
+    }
+
+    admitted := make(chan int, 3)
+    for i := 0; i < 3; i++ {
+        go func(i int) {
+            c.Add()
+            admitted <- i
+        }(i)
+        // 保证等待顺序
+        for c.Waiting() != i+1 {
+            time.Sleep(time.Millisecond)
+        }
+    }
+
+    // 调小上限不影响已占用的项目
+    c.SetLimit(1)
+    if c.Current() != 2 {
+        t.Errorf("current = %d", c.Current())
+    }
+    c.Done()
+    c.Done()
+    if i := <-admitted; i != 0 {
+        t.Errorf("应按先来先得顺序放行, got %d", i)
+    }
+    select {
+    case i := <-admitted:
+        t.Fatalf("超出上限仍放行了 %d", i)
+    case <-time.After(10 * time.Millisecond):
+    }
+
+    c.SetLimit(3)
+    if a, b := <-admitted, <-admitted; a+b != 3 {
+        t.Errorf("got %d %d", a, b)
+    }
+    if c.Current() != 3 || c.Waiting() != 0 {
+        t.Errorf("current=%d waiting=%d", c.Current(), c.Waiting())
+    }
+    for i := 0; i < 3; i++ {
+        c.Done()
+    }
+    c.Wait()
+}
+
+func TestCCZeroLimit(t *testing.T) {
+    c := NewCC(0).(*cc)
+    if c.tryAcquire() {
+        t.Fatal("上限为 0 时不应放行")
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    defer cancel()
+    if err := c.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) || c.Waiting() != 0 {
+        t.Errorf("err=%v waiting=%d", err, c.Waiting())
+    }
+    done := make(chan struct{})
+    go func() {
+        c.Add()
+        c.Done()
+        close(done)
+    }()
+    for c.Waiting() != 1 {
+        time.Sleep(time.Millisecond)
+    }
+    c.SetLimit(1)
+    <-done
+    c.Wait()
+}
+
+func TestCCWeightedPriority(t *testing.T) {
+    c := NewCC(4)
+    if err := c.AddN(context.Background(), 3); err != nil || c.Current() != 3 {
+        t.Fatalf("err=%v current=%d", err, c.Current())
+    }
+
+    admitted := make(chan string, 3)
+    add := func(name string, weight, priority int) {
+        go func() {
+            c.AddWithPriority(context.Background(), weight, priority)
+            admitted <- name
+        }()
+    }
+    // 大权重请求在队首等待时, 后来的小请求即使放得下也不能插队
+    add("big", 4, 0)
+    for c.Waiting() != 1 {
+        time.Sleep(time.Millisecond)
+    }
+    add("small", 1, 0)
+    for c.Waiting() != 2 {
+        time.Sleep(time.Millisecond)
+    }
+    add("urgent", 1, 10)
+    // 高优先级排到队首, 且名额足够时立即放行
+    if name := <-admitted; name != "urgent" {
+        t.Fatalf("got %s", name)
+    }
+    select {
+    case name := <-admitted:
+        t.Fatalf("big 未放行前不应放行 %s", name)
+    case <-time.After(10 * time.Millisecond):
+    }
+
+    c.DoneN(3)
+    c.Done()
+    if name := <-admitted; name != "big" {
+        t.Fatalf("got %s", name)
+    }
+    c.DoneN(4)
+    if name := <-admitted; name != "small" {
+        t.Fatalf("got %s", name)
+    }
+    c.Done()
+    c.Wait()
+
+    if err := c.AddN(context.Background(), -1); !errors.Is(err, ErrExceptionInvalidArgs) {
+        t.Errorf("err = %v", err)
+    }
+    // 超过上限的请求在空闲时单独放行
+    if err := c.AddN(context.Background(), 10); err != nil {
+        t.Errorf("err = %v", err)
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    defer cancel()
+    if err := c.AddN(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("err = %v", err)
+    }
+    c.DoneN(10)
+    c.Wait()
+}
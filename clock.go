@@ -0,0 +1,198 @@
+package rr
+
+import (
+    "sort"
+    "sync"
+    "time"
+)
+
+// 时钟抽象, 便于在测试中用 ManualClock 控制时间
+type Clock interface {
+    Now() time.Time
+    NewTimer(d time.Duration) ClockTimer
+    // d 之后调用 f; 返回的定时器 C() 为 nil
+    AfterFunc(d time.Duration, f func()) ClockTimer
+}
+
+type ClockTimer interface {
+    C() <-chan time.Time
+    // 定时器已触发或已停止时返回 false
+    Stop() bool
+    // 重新计时, 定时器仍在等待时返回 true
+    Reset(d time.Duration) bool
+}
+
+// 基于 time 包的系统时钟
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+    return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) ClockTimer {
+    return &systemTimer{t: time.NewTimer(d)}
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+    return &systemTimer{t: time.AfterFunc(d, f)}
+}
+
+type systemTimer struct {
+    t *time.Timer
+}
+
+func (r *systemTimer) C() <-chan time.Time {
+    return r.t.C
+}
+
+func (r *systemTimer) Stop() bool {
+    return r.t.Stop()
+}
+
+func (r *systemTimer) Reset(d time.Duration) bool {
+    return r.t.Reset(d)
+}
+
+// 手动推进的时钟, 只有调用 Advance/Set 时时间才会前进, 到期的定时器在 Advance 中按到期顺序同步触发
+type ManualClock struct {
+    mu     sync.Mutex
+    now    time.Time
+    timers []*manualTimer
+}
+
+func NewManualClock(now time.Time) *ManualClock {
+    return &ManualClock{now: now}
+}
+
+func (r *ManualClock) Now() time.Time {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.now
+}
+
+func (r *ManualClock) NewTimer(d time.Duration) ClockTimer {
+    return r.newTimer(d, nil)
+}
+
+func (r *ManualClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+    return r.newTimer(d, f)
+}
+
+func (r *ManualClock) newTimer(d time.Duration, f func()) *manualTimer {
+    t := &manualTimer{clock: r, fn: f}
+    if f == nil {
+        t.ch = make(chan time.Time, 1)
+    }
+    r.mu.Lock()
+    r.schedule(t, d)
+    r.mu.Unlock()
+    return t
+}
+
+// 推进时间并按到期顺序触发定时器
+// AfterFunc 的回调在调用方 goroutine 中同步执行, 执行时的当前时间为其到期时间
+// NewTimer 的通道收到的是到期时间, 接收方调用 Now 得到的是推进后的时间, 与定时器延迟触发时一致
+func (r *ManualClock) Advance(d time.Duration) {
+    r.mu.Lock()
+    r.fire(r.now.Add(d))
+}
+
+// 把时间设置为 t, 不允许倒退
+func (r *ManualClock) Set(t time.Time) {
+    r.mu.Lock()
+    if t.Before(r.now) {
+        t = r.now
+    }
+    r.fire(t)
+}
+
+// 等待中的定时器数量
+func (r *ManualClock) PendingTimers() int {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return len(r.timers)
+}
+
+// 依次触发到期时间不晚于 target 的定时器, 回调中新建的定时器同样会被触发, 最后把时间设置为 target
+// 调用方需持有锁, 返回时已解锁
+func (r *ManualClock) fire(target time.Time) {
+    for len(r.timers) > 0 && !r.timers[0].when.After(target) {
+        t := r.timers[0]
+        r.timers = r.timers[1:]
+        // 通道定时器不执行代码, 持有锁发送, 接收方不会读到中间时间并在本次推进中重新计时
+        if t.fn == nil {
+            select {
+            case t.ch <- t.when:
+            default:
+            }
+            continue
+        }
+        r.now = t.when
+        r.mu.Unlock()
+        t.fn()
+        r.mu.Lock()
+    }
+    r.now = target
+    r.mu.Unlock()
+}
+
+// 按到期时间插入, 非正的时长立即触发, 调用方需持有锁
+// 立即触发的回调在新的 goroutine 中执行, 避免调用方持有自己的锁时死锁
+func (r *ManualClock) schedule(t *manualTimer, d time.Duration) {
+    if d <= 0 {
+        if t.fn != nil {
+            go t.fn()
+            return
+        }
+        select {
+        case t.ch <- r.now:
+        default:
+        }
+        return
+    }
+    t.when = r.now.Add(d)
+    i := sort.Search(len(r.timers), func(i int) bool {
+        return r.timers[i].when.After(t.when)
+    })
+    r.timers = append(r.timers, nil)
+    copy(r.timers[i+1:], r.timers[i:])
+    r.timers[i] = t
+}
+
+// 移除等待中的定时器, 调用方需持有锁
+func (r *ManualClock) remove(t *manualTimer) bool {
+    for i, x := range r.timers {
+        if x == t {
+            r.timers = append(r.timers[:i], r.timers[i+1:]...)
+            return true
+        }
+    }
+    return false
+}
+
+type manualTimer struct {
+    clock *ManualClock
+    when  time.Time
+    ch    chan time.Time
+    fn    func()
+}
+
+func (r *manualTimer) C() <-chan time.Time {
+    return r.ch
+}
+
+func (r *manualTimer) Stop() bool {
+    r.clock.mu.Lock()
+    defer r.clock.mu.Unlock()
+    return r.clock.remove(r)
+}
+
+func (r *manualTimer) Reset(d time.Duration) bool {
+    r.clock.mu.Lock()
+    active := r.clock.remove(r)
+    r.clock.schedule(r, d)
+    r.clock.mu.Unlock()
+    return active
+}
@@ -0,0 +1,56 @@
+package rr
+
+import (
+    "testing"
+    "time"
+)
+
+func TestManualClock(t *testing.T) {
+    start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    c := NewManualClock(start)
+    timer := c.NewTimer(10 * time.Second)
+    var fired []string
+    c.AfterFunc(5*time.Second, func() {
+        fired = append(fired, "5s")
+    })
+    stopped := c.AfterFunc(7*time.Second, func() {
+        fired = append(fired, "7s")
+    })
+    if c.PendingTimers() != 3 {
+        t.Fatalf("pending = %d", c.PendingTimers())
+    }
+    if !stopped.Stop() || stopped.Stop() {
+        t.Error("只有第一次 Stop 返回 true")
+    }
+
+    c.Advance(6 * time.Second)
+    if len(fired) != 1 || fired[0] != "5s" {
+        t.Errorf("fired = %v", fired)
+    }
+    select {
+    case <-timer.C():
+        t.Fatal("未到期的定时器不应触发")
+    default:
+    }
+
+    c.Advance(4 * time.Second)
+    select {
+    case now := <-timer.C():
+        if !now.Equal(start.Add(10 * time.Second)) {
+            t.Errorf("now = %v", now)
+        }
+    default:
+        t.Fatal("定时器应已触发")
+    }
+    if timer.Reset(time.Second) {
+        t.Error("已触发的定时器 Reset 应返回 false")
+    }
+    c.Set(start)
+    if !c.Now().Equal(start.Add(10 * time.Second)) {
+        t.Error("Set 不应让时间倒退")
+    }
+    c.Advance(time.Second)
+    if _, ok := <-timer.C(); !ok || c.PendingTimers() != 0 {
+        t.Errorf("pending = %d", c.PendingTimers())
+    }
+}
@@ -0,0 +1,195 @@
+package rr
+
+import (
+    "strconv"
+    "strings"
+    "time"
+)
+
+// 计划: 返回严格晚于 after 的下一次执行时间, 没有下一次时返回零值
+type Schedule interface {
+    Next(after time.Time) time.Time
+}
+
+type cronField struct {
+    min, max int
+    names    map[string]int
+}
+
+var (
+    cronSecond = cronField{0, 59, nil}
+    cronMinute = cronField{0, 59, nil}
+    cronHour   = cronField{0, 23, nil}
+    cronDom    = cronField{1, 31, nil}
+    cronMonth  = cronField{1, 12, map[string]int{
+        "jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+        "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+    }}
+    // 0 与 7 都表示周日
+    cronDow = cronField{0, 7, map[string]int{
+        "sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+    }}
+)
+
+const cronAllHours = 1<<24 - 1
+
+// cron 表达式
+type CronSchedule struct {
+    second, minute, hour, dom, month, dow uint64
+    // 日与周都有限制时满足其一即可, 以 * 开头的字段不算限制
+    domAny, dowAny bool
+    loc            *time.Location
+}
+
+// 解析 5 段(分 时 日 月 周)或 6 段(秒 分 时 日 月 周)的 cron 表达式, 按 loc 计算时间, loc 为 nil 时使用 time.Local
+// 每段支持 *, ?, 数字, 名称(jan-dec, sun-sat), a-b, a,b, */n, a-b/n
+// 夏令时跳过的时间不会执行
+func ParseCron(expr string, loc *time.Location) (*CronSchedule, error) {
+    fields := strings.Fields(expr)
+    switch len(fields) {
+    case 5:
+        fields = append([]string{"0"}, fields...)
+    case 6:
+    default:
+        return nil, NewExceptionT("cron: expected 5 or 6 fields: " + expr, ErrExceptionInvalidArgs)
+    }
+    if loc == nil {
+        loc = time.Local
+    }
+    r := &CronSchedule{loc: loc}
+    var err error
+    targets := []*uint64{&r.second, &r.minute, &r.hour, &r.dom, &r.month, &r.dow}
+    for i, f := range []cronField{cronSecond, cronMinute, cronHour, cronDom, cronMonth, cronDow} {
+        if *targets[i], err = f.parse(fields[i]); err != nil {
+            return nil, err
+        }
+    }
+    // 周日统一为 0
+    if r.dow&(1<<7) != 0 {
+        r.dow |= 1
+    }
+    // 以 * 开头(含 */n)视为不限制, 与 vixie cron 一致
+    r.domAny = strings.HasPrefix(fields[3], "*") || fields[3] == "?"
+    r.dowAny = strings.HasPrefix(fields[5], "*") || fields[5] == "?"
+    return r, nil
+}
+
+func (f cronField) parse(expr string) (uint64, error) {
+    var bits uint64
+    for _, part := range strings.Split(expr, ",") {
+        lo, hi, step := f.min, f.max, 1
+        rng := part
+        if i := strings.IndexByte(part, '/'); i >= 0 {
+            n, err := strconv.Atoi(part[i+1:])
+            if err != nil || n <= 0 {
+                return 0, NewExceptionT("cron: invalid step: " + part, ErrExceptionInvalidArgs)
+            }
+            rng, step = part[:i], n
+        }
+        switch {
+        case rng == "*" || rng == "?":
+        case strings.Contains(rng, "-"):
+            a, b, _ := strings.Cut(rng, "-")
+            var err error
+            if lo, err = f.value(a); err != nil {
+                return 0, err
+            }
+            if hi, err = f.value(b); err != nil {
+                return 0, err
+            }
+        default:
+            v, err := f.value(rng)
+            if err != nil {
+                return 0, err
+            }
+            lo = v
+            // "a/n" 表示从 a 开始到最大值
+            if step == 1 {
+                hi = v
+            }
+        }
+        if lo > hi {
+            return 0, NewExceptionT("cron: invalid range: " + part, ErrExceptionInvalidArgs)
+        }
+        for v := lo; v <= hi; v += step {
+            bits |= 1 << v
+        }
+    }
+    return bits, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+    if v, ok := f.names[strings.ToLower(s)]; ok {
+        return v, nil
+    }
+    v, err := strconv.Atoi(s)
+    if err != nil || v < f.min || v > f.max {
+        return 0, NewExceptionT("cron: invalid value: " + s, ErrExceptionInvalidArgs)
+    }
+    return v, nil
+}
+
+func (r *CronSchedule) Next(after time.Time) time.Time {
+    t := after.In(r.loc)
+    // 从下一个整秒开始
+    t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+    yearLimit := t.Year() + 5
+
+WRAP:
+    if t.Year() > yearLimit {
+        return time.Time{}
+    }
+    for r.month&(1<<uint(t.Month())) == 0 {
+        t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, r.loc)
+        if t.Month() == time.January {
+            goto WRAP
+        }
+    }
+    for !r.dayMatch(t) {
+        t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, r.loc)
+        if t.Day() == 1 {
+            goto WRAP
+        }
+    }
+    for r.hour&(1<<uint(t.Hour())) == 0 {
+        day := t.Day()
+        // 按绝对时间前进到下一个整点, 夏令时跳过的小时不会导致死循环
+        t = t.Add(time.Hour - time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second)
+        if t.Day() != day || t.Hour() == 0 {
+            goto WRAP
+        }
+    }
+    for r.minute&(1<<uint(t.Minute())) == 0 {
+        t = t.Truncate(time.Minute).Add(time.Minute)
+        if t.Minute() == 0 {
+            goto WRAP
+        }
+    }
+    for r.second&(1<<uint(t.Second())) == 0 {
+        t = t.Add(time.Second)
+        if t.Second() == 0 {
+            goto WRAP
+        }
+    }
+    // 夏令时结束时重复的时段: 小时有限制的表达式只在第一次经过时执行, 与 vixie cron 一致
+    if r.hour != cronAllHours {
+        if start, _ := t.ZoneBounds(); !start.IsZero() {
+            _, off := t.Zone()
+            _, prev := start.Add(-time.Second).Zone()
+            if d := time.Duration(prev-off) * time.Second; d > 0 && t.Sub(start) < d {
+                t = start.Add(d)
+                goto WRAP
+            }
+        }
+    }
+    return t
+}
+
+func (r *CronSchedule) dayMatch(t time.Time) bool {
+    dom := r.dom&(1<<uint(t.Day())) != 0
+    dow := r.dow&(1<<uint(t.Weekday())) != 0
+    if r.domAny || r.dowAny {
+        return dom && dow
+    }
+    return dom || dow
+}
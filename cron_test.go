@@ -0,0 +1,86 @@
+package rr
+
+import (
+    "errors"
+    "testing"
+    "time"
+)
+
+func TestParseCron(t *testing.T) {
+    shanghai, _ := time.LoadLocation("Asia/Shanghai")
+    base := time.Date(2024, 1, 31, 10, 30, 15, 0, time.UTC)
+    cases := []struct {
+        expr string
+        loc  *time.Location
+        want time.Time
+    }{
+        {"* * * * *", time.UTC, time.Date(2024, 1, 31, 10, 31, 0, 0, time.UTC)},
+        {"*/15 * * * * *", time.UTC, time.Date(2024, 1, 31, 10, 30, 30, 0, time.UTC)},
+        {"0 9 * * mon-fri", time.UTC, time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)},
+        {"0 0 1 */3 *", time.UTC, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+        {"0 0 29 feb *", time.UTC, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+        {"0 0 31 * *", time.UTC, time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)},
+        // 日与周都有限制时满足其一即可
+        {"0 0 15 * 7", time.UTC, time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC)},
+        {"0 19 * * *", shanghai, time.Date(2024, 1, 31, 19, 0, 0, 0, shanghai)},
+        {"5,10 0 * * ?", time.UTC, time.Date(2024, 2, 1, 0, 5, 0, 0, time.UTC)},
+        // */n 不算限制, 日与周需同时满足: 2024-02-05 为周一且为奇数日
+        {"0 0 */2 * mon", time.UTC, time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC)},
+    }
+    for _, c := range cases {
+        s, err := ParseCron(c.expr, c.loc)
+        if err != nil {
+            t.Errorf("%s: %v", c.expr, err)
+            continue
+        }
+        if got := s.Next(base); !got.Equal(c.want) {
+            t.Errorf("%s: got %v, want %v", c.expr, got, c.want)
+        }
+    }
+
+    for _, expr := range []string{"* * * *", "60 * * * *", "* * * * mon-", "*/0 * * * *", "5-1 * * * *"} {
+        if _, err := ParseCron(expr, nil); !errors.Is(err, ErrExceptionInvalidArgs) {
+            t.Errorf("%q err = %v", expr, err)
+        }
+    }
+    if _, err := ParseCron("60 * * * *", nil); err == nil || err.Error() != "cron: invalid value: 60" {
+        t.Errorf("错误消息应包含详情, err = %v", err)
+    }
+
+    never, _ := ParseCron("0 0 30 feb *", time.UTC)
+    if !never.Next(base).IsZero() {
+        t.Error("不存在的日期应返回零值")
+    }
+}
+
+func TestCronDST(t *testing.T) {
+    ny, err := time.LoadLocation("America/New_York")
+    if err != nil {
+        t.Skip(err)
+    }
+    cases := []struct {
+        name  string
+        expr  string
+        after time.Time
+        want  time.Time
+    }{
+        // 2026-03-08 02:00 跳到 03:00
+        {"跨过跳过的小时", "0 9 * * *", time.Date(2026, 3, 7, 12, 0, 0, 0, ny), time.Date(2026, 3, 8, 9, 0, 0, 0, ny)},
+        {"跳过的时间不执行", "30 2 * * *", time.Date(2026, 3, 7, 12, 0, 0, 0, ny), time.Date(2026, 3, 9, 2, 30, 0, 0, ny)},
+        // 2026-11-01 02:00 EDT 回到 01:00 EST
+        {"重复的时段首次执行", "30 1 * * *", time.Date(2026, 11, 1, 0, 0, 0, 0, ny), time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC)},
+        {"重复的时段不再执行", "30 1 * * *", time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC), time.Date(2026, 11, 2, 1, 30, 0, 0, ny)},
+        {"小时不限时重复的时段照常执行", "30 * * * *", time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC), time.Date(2026, 11, 1, 6, 30, 0, 0, time.UTC)},
+    }
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            s, err := ParseCron(c.expr, ny)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if got := s.Next(c.after); !got.Equal(c.want) {
+                t.Errorf("%s: got %v, want %v", c.expr, got, c.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,317 @@
+package rr
+
+import (
+    "sync"
+    "time"
+)
+
+type debounceOptions struct {
+    leading  bool
+    trailing bool
+    maxWait  time.Duration
+    clock    Clock
+}
+
+type DebounceOption func(*debounceOptions)
+
+// 在一轮调用的开始立即执行, Debounce 默认 false, Throttle 默认 true
+func DebounceLeading(enable bool) DebounceOption {
+    return func(r *debounceOptions) {
+        r.leading = enable
+    }
+}
+
+// 在一轮调用结束后以最后一次的参数执行, 默认 true
+func DebounceTrailing(enable bool) DebounceOption {
+    return func(r *debounceOptions) {
+        r.trailing = enable
+    }
+}
+
+// 持续调用时最多延迟 d 就执行一次, 默认不限制
+func DebounceMaxWait(d time.Duration) DebounceOption {
+    return func(r *debounceOptions) {
+        if d > 0 {
+            r.maxWait = d
+        }
+    }
+}
+
+// 使用的时钟, 默认 SystemClock
+func DebounceClock(c Clock) DebounceOption {
+    return func(r *debounceOptions) {
+        if c != nil {
+            r.clock = c
+        }
+    }
+}
+
+// 防抖: 停止调用 wait 之后才以最后一次的参数执行 fn
+// fn 不会并发执行; 前沿执行在调用方 goroutine 中, 后沿执行在定时器 goroutine 中
+type Debouncer[T any] struct {
+    fn   func(T)
+    wait time.Duration
+    opts debounceOptions
+
+    mu         sync.Mutex
+    callMu     sync.Mutex
+    timer      ClockTimer
+    gen        uint64
+    burstStart time.Time
+    pending    bool
+    last       T
+}
+
+func Debounce[T any](fn func(T), wait time.Duration, opts ...DebounceOption) *Debouncer[T] {
+    return newDebouncer(fn, wait, debounceOptions{trailing: true}, opts)
+}
+
+func newDebouncer[T any](fn func(T), wait time.Duration, o debounceOptions, opts []DebounceOption) *Debouncer[T] {
+    o.clock = SystemClock
+    for _, opt := range opts {
+        opt(&o)
+    }
+    return &Debouncer[T]{fn: fn, wait: wait, opts: o}
+}
+
+// 触发一次调用
+func (d *Debouncer[T]) Call(v T) {
+    d.mu.Lock()
+    now := d.opts.clock.Now()
+    if d.timer == nil {
+        d.burstStart = now
+        d.schedule(d.wait)
+        if d.opts.leading {
+            d.mu.Unlock()
+            d.invoke(v)
+            return
+        }
+        d.last = v
+        d.pending = true
+        d.mu.Unlock()
+        return
+    }
+    d.last = v
+    d.pending = true
+    // 持续调用超过 maxWait 时立即执行
+    if at := d.deadline(now); at.After(now) {
+        d.schedule(at.Sub(now))
+        d.mu.Unlock()
+        return
+    }
+    v, ok := d.expire()
+    d.mu.Unlock()
+    if ok {
+        d.invoke(v)
+    }
+}
+
+// 立即执行等待中的调用并结束本轮
+func (d *Debouncer[T]) Flush() {
+    d.mu.Lock()
+    v, ok := d.last, d.pending
+    d.stop()
+    d.mu.Unlock()
+    if ok {
+        d.invoke(v)
+    }
+}
+
+// 丢弃等待中的调用并结束本轮
+func (d *Debouncer[T]) Cancel() {
+    d.mu.Lock()
+    d.stop()
+    d.mu.Unlock()
+}
+
+// 是否有等待执行的调用
+func (d *Debouncer[T]) Pending() bool {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    return d.pending
+}
+
+// 本轮的下一次执行时间, 调用方需持有锁
+func (d *Debouncer[T]) deadline(now time.Time) time.Time {
+    at := now.Add(d.wait)
+    if d.opts.maxWait > 0 {
+        if limit := d.burstStart.Add(d.opts.maxWait); limit.Before(at) {
+            at = limit
+        }
+    }
+    return at
+}
+
+// 调用方需持有锁
+func (d *Debouncer[T]) schedule(after time.Duration) {
+    if d.timer != nil {
+        d.timer.Stop()
+    }
+    d.gen++
+    gen := d.gen
+    d.timer = d.opts.clock.AfterFunc(after, func() {
+        d.fire(gen)
+    })
+}
+
+// 调用方需持有锁
+func (d *Debouncer[T]) stop() {
+    if d.timer != nil {
+        d.timer.Stop()
+        d.timer = nil
+    }
+    d.gen++
+    d.pending = false
+    var zero T
+    d.last = zero
+}
+
+func (d *Debouncer[T]) fire(gen uint64) {
+    d.mu.Lock()
+    if gen != d.gen {
+        d.mu.Unlock()
+        return
+    }
+    v, ok := d.expire()
+    d.mu.Unlock()
+    if ok {
+        d.invoke(v)
+    }
+}
+
+// 本轮到期, 返回需要后沿执行的参数, 调用方需持有锁
+func (d *Debouncer[T]) expire() (T, bool) {
+    v, ok := d.last, d.pending && d.opts.trailing
+    if ok && d.opts.leading {
+        // 启用前沿时, 后沿执行后再冷却一个周期, 避免紧随其后的调用立即触发前沿执行
+        d.pending = false
+        var zero T
+        d.last = zero
+        d.burstStart = d.opts.clock.Now()
+        d.schedule(d.wait)
+    } else {
+        d.stop()
+    }
+    return v, ok
+}
+
+func (d *Debouncer[T]) invoke(v T) {
+    d.callMu.Lock()
+    defer d.callMu.Unlock()
+    d.fn(v)
+}
+
+// 节流: 每个 interval 内最多执行一次, 默认在开始时立即执行, 并在周期结束时以最后一次的参数补执行
+type Throttler[T any] struct {
+    *Debouncer[T]
+}
+
+func Throttle[T any](fn func(T), interval time.Duration, opts ...DebounceOption) *Throttler[T] {
+    o := debounceOptions{leading: true, trailing: true, maxWait: interval}
+    return &Throttler[T]{newDebouncer(fn, interval, o, opts)}
+}
+
+type coalescerOptions struct {
+    clock Clock
+}
+
+type CoalescerOption func(*coalescerOptions)
+
+// 使用的时钟, 默认 SystemClock
+func CoalescerClock(c Clock) CoalescerOption {
+    return func(r *coalescerOptions) {
+        if c != nil {
+            r.clock = c
+        }
+    }
+}
+
+// 批量合并: 积累到 size 个, 或首个值加入 interval 之后, 把整批交给 handler
+// handler 不会并发执行
+type Coalescer[T any] struct {
+    handler  func([]T)
+    size     int
+    interval time.Duration
+    clock    Clock
+
+    mu     sync.Mutex
+    callMu sync.Mutex
+    batch  []T
+    timer  ClockTimer
+    gen    uint64
+}
+
+func NewCoalescer[T any](handler func([]T), size int, interval time.Duration, opts ...CoalescerOption) *Coalescer[T] {
+    o := coalescerOptions{clock: SystemClock}
+    for _, opt := range opts {
+        opt(&o)
+    }
+    return &Coalescer[T]{handler: handler, size: max(size, 1), interval: interval, clock: o.clock}
+}
+
+// 加入一个值, 达到 size 时在调用方 goroutine 中执行 handler
+func (c *Coalescer[T]) Add(v T) {
+    c.mu.Lock()
+    c.batch = append(c.batch, v)
+    if len(c.batch) >= c.size {
+        batch := c.take()
+        c.mu.Unlock()
+        c.invoke(batch)
+        return
+    }
+    if len(c.batch) == 1 {
+        c.gen++
+        gen := c.gen
+        c.timer = c.clock.AfterFunc(c.interval, func() {
+            c.fire(gen)
+        })
+    }
+    c.mu.Unlock()
+}
+
+// 立即处理当前积累的值
+func (c *Coalescer[T]) Flush() {
+    c.mu.Lock()
+    batch := c.take()
+    c.mu.Unlock()
+    c.invoke(batch)
+}
+
+// 当前积累的数量
+func (c *Coalescer[T]) Len() int {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return len(c.batch)
+}
+
+func (c *Coalescer[T]) fire(gen uint64) {
+    c.mu.Lock()
+    if gen != c.gen {
+        c.mu.Unlock()
+        return
+    }
+    batch := c.take()
+    c.mu.Unlock()
+    c.invoke(batch)
+}
+
+// 取出当前批次并停止定时器, 调用方需持有锁
+func (c *Coalescer[T]) take() []T {
+    if c.timer != nil {
+        c.timer.Stop()
+        c.timer = nil
+    }
+    c.gen++
+    batch := c.batch
+    c.batch = nil
+    return batch
+}
+
+func (c *Coalescer[T]) invoke(batch []T) {
+    if len(batch) == 0 {
+        return
+    }
+    c.callMu.Lock()
+    defer c.callMu.Unlock()
+    c.handler(batch)
+}
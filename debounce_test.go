@@ -0,0 +1,116 @@
+package rr
+
+import (
+    "reflect"
+    "testing"
+    "time"
+)
+
+func TestDebounce(t *testing.T) {
+    c := NewManualClock(time.Now())
+    var got []int
+    d := Debounce(func(v int) {
+        got = append(got, v)
+    }, 100*time.Millisecond, DebounceClock(c))
+    d.Call(1)
+    c.Advance(50 * time.Millisecond)
+    d.Call(2)
+    c.Advance(50 * time.Millisecond)
+    if len(got) != 0 || !d.Pending() {
+        t.Fatalf("持续调用时不应执行, got = %v", got)
+    }
+    c.Advance(50 * time.Millisecond)
+    if !reflect.DeepEqual(got, []int{2}) || d.Pending() {
+        t.Fatalf("got = %v", got)
+    }
+
+    d.Call(3)
+    d.Flush()
+    d.Call(4)
+    d.Cancel()
+    c.Advance(time.Second)
+    if !reflect.DeepEqual(got, []int{2, 3}) {
+        t.Errorf("got = %v", got)
+    }
+}
+
+func TestDebounceLeadingMaxWait(t *testing.T) {
+    c := NewManualClock(time.Now())
+    var got []int
+    d := Debounce(func(v int) {
+        got = append(got, v)
+    }, 100*time.Millisecond, DebounceClock(c), DebounceLeading(true), DebounceTrailing(false))
+    d.Call(1)
+    d.Call(2)
+    c.Advance(200 * time.Millisecond)
+    d.Call(3)
+    if !reflect.DeepEqual(got, []int{1, 3}) {
+        t.Errorf("只在前沿执行, got = %v", got)
+    }
+
+    got = nil
+    m := Debounce(func(v int) {
+        got = append(got, v)
+    }, 100*time.Millisecond, DebounceClock(c), DebounceMaxWait(250*time.Millisecond))
+    for i := 1; i <= 6; i++ {
+        m.Call(i)
+        c.Advance(60 * time.Millisecond)
+    }
+    // 第 5 次调用时已超过 maxWait
+    if !reflect.DeepEqual(got, []int{5}) {
+        t.Errorf("got = %v", got)
+    }
+    c.Advance(time.Second)
+    if !reflect.DeepEqual(got, []int{5, 6}) {
+        t.Errorf("got = %v", got)
+    }
+}
+
+func TestThrottle(t *testing.T) {
+    c := NewManualClock(time.Now())
+    var got []int
+    th := Throttle(func(v int) {
+        got = append(got, v)
+    }, 100*time.Millisecond, DebounceClock(c))
+    for i := 0; i < 25; i++ {
+        th.Call(i)
+        c.Advance(10 * time.Millisecond)
+    }
+    // 0ms 前沿执行, 之后每 100ms 以最后一次的参数执行
+    if !reflect.DeepEqual(got, []int{0, 9, 19}) {
+        t.Errorf("got = %v", got)
+    }
+    c.Advance(time.Second)
+    if !reflect.DeepEqual(got, []int{0, 9, 19, 24}) {
+        t.Errorf("got = %v", got)
+    }
+    th.Call(100)
+    if got[len(got)-1] != 100 {
+        t.Errorf("空闲后应立即执行, got = %v", got)
+    }
+}
+
+func TestCoalescer(t *testing.T) {
+    c := NewManualClock(time.Now())
+    var batches [][]int
+    co := NewCoalescer(func(batch []int) {
+        batches = append(batches, batch)
+    }, 3, time.Second, CoalescerClock(c))
+    for i := 1; i <= 4; i++ {
+        co.Add(i)
+    }
+    if !reflect.DeepEqual(batches, [][]int{{1, 2, 3}}) || co.Len() != 1 {
+        t.Fatalf("batches = %v", batches)
+    }
+    c.Advance(time.Second)
+    co.Add(5)
+    co.Flush()
+    co.Flush()
+    if !reflect.DeepEqual(batches, [][]int{{1, 2, 3}, {4}, {5}}) {
+        t.Errorf("batches = %v", batches)
+    }
+    c.Advance(time.Second)
+    if len(batches) != 3 || c.PendingTimers() != 0 {
+        t.Errorf("batches = %v", batches)
+    }
+}
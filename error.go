@@ -1,23 +1,78 @@
 package rr
 
-import "strings"
+import (
+    "errors"
+    "fmt"
+    "io"
+    "log/slog"
+    "runtime"
+    "strconv"
+    "strings"
+    "sync/atomic"
+)
+
+var (
+    // 异常链最大长度, <= 0 表示不限制; 超出的部分会被丢弃并记录数量
+    ExceptionMaxStack = 0
 
-const (
-    ExceptionMaxStack = 10
+    // 关闭调用位置采集, 零值表示开启
+    exceptionFramesDisabled atomic.Bool
 )
 
 var (
-    ErrExceptionNetwork         = NewExceptionT("Network error")
-    ErrExceptionNotFound        = NewExceptionT("Not found")
-    ErrExceptionServer          = NewExceptionT("Server error")
-    ErrExceptionTimeout         = NewExceptionT("Timeout")
-    ErrExceptionUnauthorized    = NewExceptionT("Unauthorized")
-    ErrExceptionForbidden       = NewExceptionT("Forbidden")
-    ErrExceptionTooManyRequests = NewExceptionT("Too many requests")
-    ErrExceptionInvalidArgs = NewExceptionT("Invalid arguments")
+    ErrExceptionNetwork         = newBuiltinException("Network error", "NETWORK_ERROR", 502, CanonicalUnavailable)
+    ErrExceptionNotFound        = newBuiltinException("Not found", "NOT_FOUND", 404, CanonicalNotFound)
+    ErrExceptionServer          = newBuiltinException("Server error", "SERVER_ERROR", 500, CanonicalInternal)
+    ErrExceptionTimeout         = newBuiltinException("Timeout", "TIMEOUT", 504, CanonicalDeadlineExceeded)
+    ErrExceptionUnauthorized    = newBuiltinException("Unauthorized", "UNAUTHORIZED", 401, CanonicalUnauthenticated)
+    ErrExceptionForbidden       = newBuiltinException("Forbidden", "FORBIDDEN", 403, CanonicalPermissionDenied)
+    ErrExceptionTooManyRequests = newBuiltinException("Too many requests", "TOO_MANY_REQUESTS", 429, CanonicalResourceExhausted)
+    ErrExceptionInvalidArgs     = newBuiltinException("Invalid arguments", "INVALID_ARGUMENTS", 400, CanonicalInvalidArgument)
+    ErrExceptionPanic           = newBuiltinException("Panic", "PANIC", 500, CanonicalInternal)
 )
 
+// 全局开关: 是否在 NewException/NewExceptionT/With* 时记录调用位置, 热点路径可关闭
+func ExceptionCaptureFrames(enable bool) {
+    exceptionFramesDisabled.Store(!enable)
+}
+
+// 异常的调用位置
+type ExceptionFrame struct {
+    File     string
+    Line     int
+    Function string
+}
+
+func (r ExceptionFrame) IsZero() bool {
+    return r.File == "" && r.Line == 0 && r.Function == ""
+}
+func (r ExceptionFrame) String() string {
+    if r.IsZero() {
+        return ""
+    }
+    return r.Function + " (" + r.File + ":" + strconv.Itoa(r.Line) + ")"
+}
+
+// skip 为 0 时返回调用 captureFrame 的函数的调用者
+func captureFrame(skip int) ExceptionFrame {
+    if exceptionFramesDisabled.Load() {
+        return ExceptionFrame{}
+    }
+    pc, file, line, ok := runtime.Caller(skip + 2)
+    if !ok {
+        return ExceptionFrame{}
+    }
+    f := ExceptionFrame{File: file, Line: line}
+    if fn := runtime.FuncForPC(pc); fn != nil {
+        f.Function = fn.Name()
+    }
+    return f
+}
+
 //  异常错误处理
+//  异常是不可变的, 所有 With* 方法都返回新值, 包级的 ErrException* 可以在多个 goroutine 中安全地继续链接
+//  With/WithException 会保留原始错误值, 可通过 errors.Is / errors.As / errors.Unwrap 访问
+//  使用 %+v 格式化可输出带调用位置的完整异常链
 type Exception interface {
     Error() string
     Is(v error) bool
@@ -27,18 +82,51 @@ type Exception interface {
     WithT(v string) Exception
     WithException(v Exception) Exception
     StackMessages() string
+    Unwrap() []error
+    // 调用位置, 第一个为创建位置, 之后依次对应链上的每一项
+    Frames() []ExceptionFrame
+    // 因超出 ExceptionMaxStack 被丢弃的条目数
+    Dropped() int
+    Format(s fmt.State, verb rune)
+    // 业务错误码 / HTTP 状态码 / 规范错误码, 未设置时取链上的值
+    Code() string
+    WithCode(code string) Exception
+    Status() int
+    WithStatus(status int) Exception
+    Canonical() CanonicalCode
+    WithCanonical(c CanonicalCode) Exception
+    MarshalJSON() ([]byte, error)
+    // 结构化字段, 随异常链传递
+    WithField(key string, value any) Exception
+    WithFields(fields map[string]any) Exception
+    Fields() map[string]any
+    Field(key string) (any, bool)
+    LogValue() slog.Value
+}
+type exceptionEntry struct {
+    err   error
+    frame ExceptionFrame
 }
 type exceptionStack struct {
-    text   string
-    with   []string
-    index  int
-    length int
+    text         string
+    cause        error
+    frame        ExceptionFrame
+    with         []exceptionEntry
+    dropped      int
+    length       int
+    code         string
+    status       int
+    canonical    CanonicalCode
+    hasCanonical bool
+    fields       map[string]any
+    // panic 时的调用栈, 不属于字段
+    stack        string
 }
 
 func (r *exceptionStack) Error() string {
     return r.text
 }
-func (r exceptionStack) IsE(exception Exception) bool {
+func (r *exceptionStack) IsE(exception Exception) bool {
     if exception == nil {
         return false
     }
@@ -51,50 +139,147 @@ func (r *exceptionStack) IsT(v string) bool {
     if v == r.text {
         return true
     }
-    for _, s := range r.with {
-        if s == v {
+    for _, e := range r.with {
+        if e.err.Error() == v {
             return true
         }
     }
     return false
 }
+
+// 供 errors.Is 使用, 按消息文本匹配, 与 IsT 一致
 func (r *exceptionStack) Is(v error) bool {
     if v == nil {
         return false
     }
+    if e, ok := v.(*exceptionStack); ok && e == r {
+        return true
+    }
     return r.IsT(v.Error())
 }
+
+// 返回原始错误与链上的所有错误, 供 errors.Is / errors.As 遍历
+func (r *exceptionStack) Unwrap() []error {
+    errs := make([]error, 0, len(r.with)+1)
+    if r.cause != nil {
+        errs = append(errs, r.cause)
+    }
+    for _, e := range r.with {
+        errs = append(errs, e.err)
+    }
+    return errs
+}
+func (r *exceptionStack) Frames() []ExceptionFrame {
+    frames := make([]ExceptionFrame, 0, len(r.with)+1)
+    frames = append(frames, r.frame)
+    for _, e := range r.with {
+        frames = append(frames, e.frame)
+    }
+    return frames
+}
+func (r *exceptionStack) Dropped() int {
+    return r.dropped
+}
 func (r *exceptionStack) StackMessages() string {
     var sb strings.Builder
     sb.Grow(r.length)
     sb.WriteString(r.text)
-    
-    for _, s := range r.with {
+
+    for _, e := range r.with {
+        s := e.err.Error()
         if s == "" {
             continue
         }
         sb.WriteString("\n")
         sb.WriteString(s)
-        
+
+    }
+    if r.dropped > 0 {
+        sb.WriteString("\n")
+        sb.WriteString(r.droppedMessage())
     }
     return sb.String()
 }
-func (r *exceptionStack) WithException(v Exception) Exception {
-    return r.WithT(v.Error())
+func (r *exceptionStack) droppedMessage() string {
+    return "... " + strconv.Itoa(r.dropped) + " more entries dropped (ExceptionMaxStack=" + strconv.Itoa(ExceptionMaxStack) + ")"
 }
-func (r *exceptionStack) WithT(v string) Exception {
-    if r.index >= ExceptionMaxStack {
+
+// %s %v 输出消息, %q 输出带引号的消息, %+v 输出带调用位置的完整异常链
+func (r *exceptionStack) Format(s fmt.State, verb rune) {
+    switch verb {
+    case 'v':
+        if s.Flag('+') {
+            r.writeTrace(s)
+            return
+        }
+        _, _ = io.WriteString(s, r.text)
+    case 's':
+        _, _ = io.WriteString(s, r.text)
+    case 'q':
+        _, _ = fmt.Fprintf(s, "%q", r.text)
+    }
+}
+func (r *exceptionStack) writeTrace(w io.Writer) {
+    _, _ = io.WriteString(w, r.text)
+    writeFrame(w, r.frame)
+    for _, e := range r.with {
+        _, _ = io.WriteString(w, "\n--- ")
+        _, _ = io.WriteString(w, e.err.Error())
+        writeFrame(w, e.frame)
+    }
+    if r.dropped > 0 {
+        _, _ = io.WriteString(w, "\n")
+        _, _ = io.WriteString(w, r.droppedMessage())
+    }
+    if s := r.panicStack(); s != "" {
+        _, _ = io.WriteString(w, "\n")
+        _, _ = io.WriteString(w, strings.TrimRight(s, "\n"))
+    }
+}
+func writeFrame(w io.Writer, f ExceptionFrame) {
+    if f.IsZero() {
+        return
+    }
+    _, _ = io.WriteString(w, "\n    at ")
+    _, _ = io.WriteString(w, f.String())
+}
+func (r *exceptionStack) WithException(v Exception) Exception {
+    if v == nil {
         return r
     }
-    r.with[r.index] = v
-    r.index++
-    return r
+    c := r.clone()
+    c.push(v, captureFrame(0))
+    return c
+}
+func (r *exceptionStack) WithT(v string) Exception {
+    c := r.clone()
+    c.push(errors.New(v), captureFrame(0))
+    return c
 }
 func (r *exceptionStack) With(v error) Exception {
     if v == nil {
         return r
     }
-    return r.WithT(v.Error())
+    c := r.clone()
+    c.push(v, captureFrame(0))
+    return c
+}
+
+// 浅拷贝, with 裁剪容量, 保证后续 append 不会写入原值的底层数组
+func (r *exceptionStack) clone() *exceptionStack {
+    c := *r
+    c.with = r.with[:len(r.with):len(r.with)]
+    return &c
+}
+
+// 仅用于尚未对外暴露的新值
+func (r *exceptionStack) push(v error, frame ExceptionFrame) {
+    if ExceptionMaxStack > 0 && len(r.with) >= ExceptionMaxStack {
+        r.dropped++
+        return
+    }
+    r.with = append(r.with, exceptionEntry{err: v, frame: frame})
+    r.length += len(v.Error()) + 1
 }
 
 func NewException(e error, with ...Exception) Exception {
@@ -102,20 +287,26 @@ func NewException(e error, with ...Exception) Exception {
     if e != nil {
         v = e.Error()
     }
-    
-    t := NewExceptionT(v)
+
+    frame := captureFrame(0)
+    t := &exceptionStack{text: v, cause: e, frame: frame, length: len(v)}
     if len(with) > 0 {
         for _, exception := range with {
-            t.WithException(exception)
+            if exception != nil {
+                t.push(exception, frame)
+            }
         }
     }
     return t
 }
 func NewExceptionT(v string, with ...Exception) Exception {
-    t := &exceptionStack{text: v, with: make([]string, ExceptionMaxStack), index: 0, length: len(v)}
+    frame := captureFrame(0)
+    t := &exceptionStack{text: v, frame: frame, length: len(v)}
     if len(with) > 0 {
         for _, exception := range with {
-            t.WithException(exception)
+            if exception != nil {
+                t.push(exception, frame)
+            }
         }
     }
     return t
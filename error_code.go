@@ -0,0 +1,275 @@
+package rr
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "strconv"
+)
+
+// gRPC 风格的规范错误码, 取值与 google.golang.org/grpc/codes 一致
+type CanonicalCode uint32
+
+const (
+    CanonicalOK CanonicalCode = iota
+    CanonicalCanceled
+    CanonicalUnknown
+    CanonicalInvalidArgument
+    CanonicalDeadlineExceeded
+    CanonicalNotFound
+    CanonicalAlreadyExists
+    CanonicalPermissionDenied
+    CanonicalResourceExhausted
+    CanonicalFailedPrecondition
+    CanonicalAborted
+    CanonicalOutOfRange
+    CanonicalUnimplemented
+    CanonicalInternal
+    CanonicalUnavailable
+    CanonicalDataLoss
+    CanonicalUnauthenticated
+)
+
+var canonicalNames = [...]string{
+    "OK", "CANCELLED", "UNKNOWN", "INVALID_ARGUMENT", "DEADLINE_EXCEEDED", "NOT_FOUND", "ALREADY_EXISTS",
+    "PERMISSION_DENIED", "RESOURCE_EXHAUSTED", "FAILED_PRECONDITION", "ABORTED", "OUT_OF_RANGE",
+    "UNIMPLEMENTED", "INTERNAL", "UNAVAILABLE", "DATA_LOSS", "UNAUTHENTICATED",
+}
+
+// 规范错误码对应的 HTTP 状态码, 与 grpc-gateway 的映射一致
+var canonicalHTTPStatus = [...]int{
+    http.StatusOK, 499, http.StatusInternalServerError, http.StatusBadRequest, http.StatusGatewayTimeout,
+    http.StatusNotFound, http.StatusConflict, http.StatusForbidden, http.StatusTooManyRequests,
+    http.StatusBadRequest, http.StatusConflict, http.StatusBadRequest, http.StatusNotImplemented,
+    http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusInternalServerError,
+    http.StatusUnauthorized,
+}
+
+func (c CanonicalCode) String() string {
+    if int(c) < len(canonicalNames) {
+        return canonicalNames[c]
+    }
+    return "CODE(" + strconv.FormatUint(uint64(c), 10) + ")"
+}
+
+// 对应的 HTTP 状态码, 未知返回 500
+func (c CanonicalCode) HTTPStatus() int {
+    if int(c) < len(canonicalHTTPStatus) {
+        return canonicalHTTPStatus[c]
+    }
+    return http.StatusInternalServerError
+}
+func (c CanonicalCode) MarshalText() ([]byte, error) {
+    return []byte(c.String()), nil
+}
+func (c *CanonicalCode) UnmarshalText(b []byte) error {
+    s := string(b)
+    for i, name := range canonicalNames {
+        if name == s {
+            *c = CanonicalCode(i)
+            return nil
+        }
+    }
+    return errors.New("rr: unknown canonical code " + strconv.Quote(s))
+}
+
+// 内置异常的错误码映射, 不记录调用位置, 由其派生的异常链从实际使用处开始记录
+func newBuiltinException(text, code string, status int, canonical CanonicalCode) Exception {
+    return &exceptionStack{text: text, length: len(text), code: code, status: status, canonical: canonical, hasCanonical: true}
+}
+
+// HTTP 状态码转换为异常, 内置映射返回对应的 ErrException*, 其它状态码返回新的异常, < 400 返回 nil
+// 与其它状态码共用内置异常的(408, 503)返回保留原状态码的副本, 保证 ExceptionToHTTPStatus 能还原
+func ExceptionFromHTTPStatus(status int) Exception {
+    switch status {
+    case http.StatusBadRequest:
+        return ErrExceptionInvalidArgs
+    case http.StatusUnauthorized:
+        return ErrExceptionUnauthorized
+    case http.StatusForbidden:
+        return ErrExceptionForbidden
+    case http.StatusNotFound:
+        return ErrExceptionNotFound
+    case http.StatusGatewayTimeout:
+        return ErrExceptionTimeout
+    case http.StatusRequestTimeout:
+        return ErrExceptionTimeout.WithStatus(status)
+    case http.StatusTooManyRequests:
+        return ErrExceptionTooManyRequests
+    case http.StatusInternalServerError:
+        return ErrExceptionServer
+    case http.StatusBadGateway:
+        return ErrExceptionNetwork
+    case http.StatusServiceUnavailable:
+        return ErrExceptionNetwork.WithStatus(status)
+    }
+    if status < 400 {
+        return nil
+    }
+    text := http.StatusText(status)
+    if text == "" {
+        text = "HTTP " + strconv.Itoa(status)
+    }
+    ex := NewExceptionT(text).WithStatus(status)
+    if status >= 500 {
+        return ex.WithCanonical(CanonicalInternal).WithException(ErrExceptionServer)
+    }
+    return ex.WithCanonical(CanonicalFailedPrecondition)
+}
+
+// 错误转换为 HTTP 状态码: nil 为 200, 异常取 Status(), context 超时/取消为 504/499, 其它为 500
+func ExceptionToHTTPStatus(err error) int {
+    if err == nil {
+        return http.StatusOK
+    }
+    var ex Exception
+    if errors.As(err, &ex) {
+        if s := ex.Status(); s > 0 {
+            return s
+        }
+    }
+    return ExceptionToCanonical(err).HTTPStatus()
+}
+
+// 错误转换为规范错误码: nil 为 OK, 异常取 Canonical(), context 超时/取消分别映射, 其它为 UNKNOWN
+func ExceptionToCanonical(err error) CanonicalCode {
+    if err == nil {
+        return CanonicalOK
+    }
+    var ex Exception
+    if errors.As(err, &ex) {
+        if c := ex.Canonical(); c != CanonicalUnknown {
+            return c
+        }
+    }
+    switch {
+    case errors.Is(err, context.DeadlineExceeded):
+        return CanonicalDeadlineExceeded
+    case errors.Is(err, context.Canceled):
+        return CanonicalCanceled
+    }
+    return CanonicalUnknown
+}
+
+func (r *exceptionStack) WithCode(code string) Exception {
+    c := r.clone()
+    c.code = code
+    return c
+}
+func (r *exceptionStack) WithStatus(status int) Exception {
+    c := r.clone()
+    c.status = status
+    return c
+}
+func (r *exceptionStack) WithCanonical(canonical CanonicalCode) Exception {
+    c := r.clone()
+    c.canonical = canonical
+    c.hasCanonical = true
+    return c
+}
+
+// 业务错误码, 未设置时取链上第一个设置了错误码的异常
+func (r *exceptionStack) Code() string {
+    if r.code != "" {
+        return r.code
+    }
+    for _, ex := range r.chainExceptions() {
+        if c := ex.Code(); c != "" {
+            return c
+        }
+    }
+    return ""
+}
+
+// HTTP 状态码, 未设置时取链上的值, 仍未找到则由 Canonical() 推导, 都没有时返回 0
+func (r *exceptionStack) Status() int {
+    if r.status > 0 {
+        return r.status
+    }
+    for _, ex := range r.chainExceptions() {
+        if s := ex.Status(); s > 0 {
+            return s
+        }
+    }
+    if c := r.Canonical(); c != CanonicalUnknown {
+        return c.HTTPStatus()
+    }
+    return 0
+}
+
+// 规范错误码, 未设置时取链上的值, 默认 CanonicalUnknown
+func (r *exceptionStack) Canonical() CanonicalCode {
+    if r.hasCanonical {
+        return r.canonical
+    }
+    for _, ex := range r.chainExceptions() {
+        if c := ex.Canonical(); c != CanonicalUnknown {
+            return c
+        }
+    }
+    return CanonicalUnknown
+}
+
+// 原始错误与链上直接包含的异常
+func (r *exceptionStack) chainExceptions() []Exception {
+    var list []Exception
+    if ex, ok := r.cause.(Exception); ok {
+        list = append(list, ex)
+    }
+    for _, e := range r.with {
+        if ex, ok := e.err.(Exception); ok {
+            list = append(list, ex)
+        }
+    }
+    return list
+}
+
+type exceptionJSON struct {
+    Code      string               `json:"code,omitempty"`
+    Message   string               `json:"message"`
+    Status    int                  `json:"status,omitempty"`
+    Canonical *CanonicalCode       `json:"canonical,omitempty"`
+    Chain     []exceptionJSONEntry `json:"chain,omitempty"`
+    Fields    map[string]any       `json:"fields,omitempty"`
+}
+type exceptionJSONEntry struct {
+    Code    string `json:"code,omitempty"`
+    Message string `json:"message"`
+}
+
+// 编码为 {"code","message","status","canonical","chain","fields"}, 可通过 JsonMarshal 等辅助函数使用
+func (r *exceptionStack) MarshalJSON() ([]byte, error) {
+    v := exceptionJSON{Code: r.Code(), Message: r.text, Status: r.Status(), Fields: r.Fields()}
+    if c := r.Canonical(); c != CanonicalUnknown {
+        v.Canonical = &c
+    }
+    for _, e := range r.with {
+        entry := exceptionJSONEntry{Message: e.err.Error()}
+        if ex, ok := e.err.(Exception); ok {
+            entry.Code = ex.Code()
+        }
+        v.Chain = append(v.Chain, entry)
+    }
+    return JsonMarshalAdapter(v)
+}
+
+// 从 JSON 还原异常, 与 MarshalJSON 对应
+func ExceptionFromJSON(data []byte) (Exception, error) {
+    var v exceptionJSON
+    if err := JsonUnmarshal(data, &v); err != nil {
+        return nil, err
+    }
+    t := &exceptionStack{text: v.Message, code: v.Code, status: v.Status, fields: v.Fields, length: len(v.Message)}
+    if v.Canonical != nil {
+        t.canonical = *v.Canonical
+        t.hasCanonical = true
+    }
+    for _, entry := range v.Chain {
+        if entry.Code != "" {
+            t.push(NewExceptionT(entry.Message).WithCode(entry.Code), ExceptionFrame{})
+            continue
+        }
+        t.push(errors.New(entry.Message), ExceptionFrame{})
+    }
+    return t, nil
+}
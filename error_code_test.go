@@ -0,0 +1,93 @@
+package rr
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "testing"
+)
+
+func TestExceptionHTTPStatus(t *testing.T) {
+    tests := []struct {
+        status int
+        want   Exception
+    }{
+        {400, ErrExceptionInvalidArgs},
+        {401, ErrExceptionUnauthorized},
+        {403, ErrExceptionForbidden},
+        {404, ErrExceptionNotFound},
+        {429, ErrExceptionTooManyRequests},
+        {500, ErrExceptionServer},
+        {502, ErrExceptionNetwork},
+        {504, ErrExceptionTimeout},
+    }
+    for _, tt := range tests {
+        t.Run(fmt.Sprint(tt.status), func(t *testing.T) {
+            if got := ExceptionFromHTTPStatus(tt.status); got != tt.want {
+                t.Errorf("ExceptionFromHTTPStatus(%d) = %v, 期望 %v", tt.status, got, tt.want)
+            }
+            if got := ExceptionToHTTPStatus(tt.want); got != tt.status {
+                t.Errorf("ExceptionToHTTPStatus(%v) = %d, 期望 %d", tt.want, got, tt.status)
+            }
+        })
+    }
+
+    // 共用内置异常的状态码保留原值
+    for _, tt := range []struct {
+        status int
+        want   Exception
+    }{{408, ErrExceptionTimeout}, {503, ErrExceptionNetwork}} {
+        got := ExceptionFromHTTPStatus(tt.status)
+        if !errors.Is(got, tt.want) || ExceptionToHTTPStatus(got) != tt.status {
+            t.Errorf("ExceptionFromHTTPStatus(%d) = %v, status=%d", tt.status, got, ExceptionToHTTPStatus(got))
+        }
+    }
+
+    if ExceptionFromHTTPStatus(204) != nil {
+        t.Errorf("2xx 应返回 nil")
+    }
+    conflict := ExceptionFromHTTPStatus(409)
+    if conflict.Status() != 409 || conflict.Error() != "Conflict" {
+        t.Errorf("409 = %v status=%d", conflict, conflict.Status())
+    }
+    if bad := ExceptionFromHTTPStatus(599); !errors.Is(bad, ErrExceptionServer) || bad.Status() != 599 {
+        t.Errorf("599 = %v status=%d", bad, bad.Status())
+    }
+
+    wrapped := fmt.Errorf("api: %w", NewExceptionT("load user").WithException(ErrExceptionNotFound))
+    if got := ExceptionToHTTPStatus(wrapped); got != 404 {
+        t.Errorf("链上继承状态码 = %d, 期望 404", got)
+    }
+    if got := ExceptionToCanonical(wrapped); got != CanonicalNotFound {
+        t.Errorf("链上继承规范码 = %v", got)
+    }
+    if got := ExceptionToHTTPStatus(context.DeadlineExceeded); got != 504 {
+        t.Errorf("DeadlineExceeded = %d", got)
+    }
+    if got := ExceptionToHTTPStatus(errors.New("x")); got != 500 {
+        t.Errorf("普通错误 = %d", got)
+    }
+}
+
+func TestExceptionJSON(t *testing.T) {
+    ex := NewExceptionT("load user").WithCode("USER_LOAD").WithException(ErrExceptionNotFound).WithT("id=1")
+    data := JsonMarshal(ex)
+    want := `{"code":"USER_LOAD","message":"load user","status":404,"canonical":"NOT_FOUND","chain":[{"code":"NOT_FOUND","message":"Not found"},{"message":"id=1"}]}`
+    if data != want {
+        t.Fatalf("JsonMarshal = %s\n期望 %s", data, want)
+    }
+
+    back, err := ExceptionFromJSON([]byte(data))
+    if err != nil {
+        t.Fatalf("ExceptionFromJSON err = %v", err)
+    }
+    if back.Code() != "USER_LOAD" || back.Status() != 404 || back.Canonical() != CanonicalNotFound {
+        t.Errorf("还原后 code=%s status=%d canonical=%v", back.Code(), back.Status(), back.Canonical())
+    }
+    if !errors.Is(back, ErrExceptionNotFound) || back.StackMessages() != ex.StackMessages() {
+        t.Errorf("还原后链 = %q", back.StackMessages())
+    }
+    if JsonMarshal(back) != want {
+        t.Errorf("再次编码 = %s", JsonMarshal(back))
+    }
+}
@@ -0,0 +1,99 @@
+package rr
+
+import (
+    "log/slog"
+    "sort"
+)
+
+func (r *exceptionStack) WithField(key string, value any) Exception {
+    return r.WithFields(map[string]any{key: value})
+}
+func (r *exceptionStack) WithFields(fields map[string]any) Exception {
+    if len(fields) == 0 {
+        return r
+    }
+    c := r.clone()
+    c.fields = make(map[string]any, len(r.fields)+len(fields))
+    for k, v := range r.fields {
+        c.fields[k] = v
+    }
+    for k, v := range fields {
+        c.fields[k] = v
+    }
+    return c
+}
+
+// 所有字段, 包括链上异常的字段, 同名时外层覆盖内层
+func (r *exceptionStack) Fields() map[string]any {
+    fields := make(map[string]any)
+    for _, ex := range r.chainExceptions() {
+        for k, v := range ex.Fields() {
+            fields[k] = v
+        }
+    }
+    for k, v := range r.fields {
+        fields[k] = v
+    }
+    return fields
+}
+func (r *exceptionStack) Field(key string) (any, bool) {
+    if v, ok := r.fields[key]; ok {
+        return v, true
+    }
+    list := r.chainExceptions()
+    for i := len(list) - 1; i >= 0; i-- {
+        if v, ok := list[i].Field(key); ok {
+            return v, true
+        }
+    }
+    return nil, false
+}
+
+// 实现 slog.LogValuer, 输出 message/code/status/chain/fields 分组, panic 异常另有 stack
+func (r *exceptionStack) LogValue() slog.Value {
+    attrs := []slog.Attr{slog.String("message", r.text)}
+    if c := r.Code(); c != "" {
+        attrs = append(attrs, slog.String("code", c))
+    }
+    if s := r.Status(); s > 0 {
+        attrs = append(attrs, slog.Int("status", s))
+    }
+    if len(r.with) > 0 {
+        chain := make([]string, 0, len(r.with))
+        for _, e := range r.with {
+            chain = append(chain, e.err.Error())
+        }
+        attrs = append(attrs, slog.Any("chain", chain))
+    }
+    if fields := r.Fields(); len(fields) > 0 {
+        keys := make([]string, 0, len(fields))
+        for k := range fields {
+            keys = append(keys, k)
+        }
+        sort.Strings(keys)
+        group := make([]any, 0, len(keys))
+        for _, k := range keys {
+            group = append(group, slog.Any(k, fields[k]))
+        }
+        attrs = append(attrs, slog.Group("fields", group...))
+    }
+    if s := r.panicStack(); s != "" {
+        attrs = append(attrs, slog.String(ExceptionFieldStack, s))
+    }
+    return slog.GroupValue(attrs...)
+}
+
+// 自身或链上异常记录的 panic 调用栈
+func (r *exceptionStack) panicStack() string {
+    if r.stack != "" {
+        return r.stack
+    }
+    for _, ex := range r.chainExceptions() {
+        if e, ok := ex.(*exceptionStack); ok {
+            if s := e.panicStack(); s != "" {
+                return s
+            }
+        }
+    }
+    return ""
+}
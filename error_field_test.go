@@ -0,0 +1,53 @@
+package rr
+
+import (
+    "bytes"
+    "log/slog"
+    "strings"
+    "testing"
+)
+
+func TestExceptionFields(t *testing.T) {
+    inner := NewExceptionT("query failed").WithFields(map[string]any{"table": "users", "id": 1})
+    ex := NewExceptionT("load user").WithException(inner).WithField("id", 2)
+
+    fields := ex.Fields()
+    if len(fields) != 2 || fields["table"] != "users" || fields["id"] != 2 {
+        t.Errorf("Fields() = %v", fields)
+    }
+    if v, ok := ex.Field("table"); !ok || v != "users" {
+        t.Errorf("Field(table) = %v, %v", v, ok)
+    }
+    if _, ok := ex.Field("missing"); ok {
+        t.Errorf("Field(missing) 不应存在")
+    }
+
+    back, err := ExceptionFromJSON([]byte(JsonMarshal(ex)))
+    if err != nil {
+        t.Fatalf("ExceptionFromJSON err = %v", err)
+    }
+    if v, _ := back.Field("table"); v != "users" {
+        t.Errorf("JSON 还原后 fields = %v", back.Fields())
+    }
+}
+
+func TestExceptionLogValue(t *testing.T) {
+    var buf bytes.Buffer
+    logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+        ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+            if len(groups) == 0 && a.Key == slog.TimeKey {
+                return slog.Attr{}
+            }
+            return a
+        },
+    }))
+    ex := NewExceptionT("load user").WithException(ErrExceptionNotFound).WithField("user_id", 7)
+    logger.Error("x", "err", ex)
+
+    got := buf.String()
+    for _, want := range []string{`err.message="load user"`, "err.code=NOT_FOUND", "err.status=404", `err.chain="[Not found]"`, "err.fields.user_id=7"} {
+        if !strings.Contains(got, want) {
+            t.Errorf("日志 %q 缺少 %q", got, want)
+        }
+    }
+}
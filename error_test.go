@@ -0,0 +1,192 @@
+package rr
+
+import (
+    "errors"
+    "fmt"
+    "io/fs"
+    "strconv"
+    "strings"
+    "sync"
+    "testing"
+)
+
+func TestExceptionIsT(t *testing.T) {
+    ex := NewExceptionT("load user").WithT("db").With(errors.New("conn refused"))
+    tests := []struct {
+        name string
+        v    string
+        want bool
+    }{
+        {"自身", "load user", true},
+        {"链上文本", "db", true},
+        {"链上错误", "conn refused", true},
+        {"空字符串", "", false},
+        {"不存在", "other", false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := ex.IsT(tt.v); got != tt.want {
+                t.Errorf("IsT(%q) = %v, 期望 %v", tt.v, got, tt.want)
+            }
+        })
+    }
+    if ex.StackMessages() != "load user\ndb\nconn refused" {
+        t.Errorf("StackMessages() = %q", ex.StackMessages())
+    }
+}
+
+func TestExceptionErrorsInterop(t *testing.T) {
+    t.Run("fmt包装后errors.Is", func(t *testing.T) {
+        ex := NewExceptionT("call api").WithException(ErrExceptionTimeout)
+        wrapped := fmt.Errorf("handler: %w", ex)
+        if !errors.Is(wrapped, ErrExceptionTimeout) {
+            t.Errorf("errors.Is(wrapped, ErrExceptionTimeout) = false")
+        }
+        if errors.Is(wrapped, ErrExceptionNotFound) {
+            t.Errorf("errors.Is(wrapped, ErrExceptionNotFound) = true")
+        }
+    })
+
+    t.Run("errors.As取回原始类型", func(t *testing.T) {
+        origin := &fs.PathError{Op: "open", Path: "/tmp/x", Err: fs.ErrNotExist}
+        ex := NewExceptionT("read config").With(origin)
+        var pathErr *fs.PathError
+        if !errors.As(fmt.Errorf("%w", ex), &pathErr) || pathErr != origin {
+            t.Errorf("errors.As 未取回 *fs.PathError")
+        }
+        if !errors.Is(ex, fs.ErrNotExist) {
+            t.Errorf("errors.Is(ex, fs.ErrNotExist) = false")
+        }
+    })
+
+    t.Run("NewException保留原始错误", func(t *testing.T) {
+        origin := fs.ErrPermission
+        ex := NewException(origin)
+        if ex.Error() != origin.Error() || !errors.Is(ex, fs.ErrPermission) {
+            t.Errorf("ex = %v", ex)
+        }
+        if got := errors.Unwrap(fmt.Errorf("%w", ex)); got != ex {
+            t.Errorf("errors.Unwrap = %v", got)
+        }
+    })
+
+    t.Run("链上包含自身", func(t *testing.T) {
+        ex := NewExceptionT("self")
+        ex = ex.WithException(ex)
+        // 自身引用不应导致 errors.Is 死循环
+        if errors.Is(ex, fs.ErrNotExist) {
+            t.Errorf("errors.Is(ex, fs.ErrNotExist) = true")
+        }
+    })
+}
+
+func TestExceptionFrames(t *testing.T) {
+    ex := NewExceptionT("outer").WithT("inner")
+    frames := ex.Frames()
+    if len(frames) != 2 {
+        t.Fatalf("len(Frames()) = %d", len(frames))
+    }
+    for i, f := range frames {
+        if !strings.HasSuffix(f.File, "error_test.go") || !strings.HasSuffix(f.Function, "TestExceptionFrames") || f.Line == 0 {
+            t.Errorf("Frames()[%d] = %+v", i, f)
+        }
+    }
+    trace := fmt.Sprintf("%+v", ex)
+    if !strings.HasPrefix(trace, "outer\n    at ") || !strings.Contains(trace, "\n--- inner\n    at ") {
+        t.Errorf("%%+v = %q", trace)
+    }
+    if s := fmt.Sprintf("%v|%s|%q", ex, ex, ex); s != `outer|outer|"outer"` {
+        t.Errorf("格式化 = %q", s)
+    }
+
+    // 内置异常没有调用位置, %+v 从 WithT 处开始
+    if f := ErrExceptionNotFound.Frames()[0]; !f.IsZero() {
+        t.Errorf("内置异常 frame = %+v", f)
+    }
+    if trace := fmt.Sprintf("%+v", ErrExceptionNotFound.WithT("user")); !strings.HasPrefix(trace, "Not found\n--- user\n    at ") {
+        t.Errorf("%%+v = %q", trace)
+    }
+
+    ExceptionCaptureFrames(false)
+    defer ExceptionCaptureFrames(true)
+    if f := NewExceptionT("hot").Frames()[0]; !f.IsZero() {
+        t.Errorf("关闭采集后 frame = %+v", f)
+    }
+}
+
+func TestExceptionMaxStack(t *testing.T) {
+    ex := NewExceptionT("root")
+    for i := 0; i < 20; i++ {
+        ex = ex.WithT(strconv.Itoa(i))
+    }
+    if ex.Dropped() != 0 || len(ex.Unwrap()) != 20 {
+        t.Fatalf("默认不限制长度, Dropped()=%d len=%d", ex.Dropped(), len(ex.Unwrap()))
+    }
+
+    ExceptionMaxStack = 3
+    defer func() { ExceptionMaxStack = 0 }()
+    ex = NewExceptionT("root")
+    for i := 0; i < 5; i++ {
+        ex = ex.WithT(strconv.Itoa(i))
+    }
+    if ex.Dropped() != 2 {
+        t.Errorf("Dropped() = %d, 期望 2", ex.Dropped())
+    }
+    if !strings.HasSuffix(ex.StackMessages(), "\n2\n... 2 more entries dropped (ExceptionMaxStack=3)") {
+        t.Errorf("StackMessages() = %q", ex.StackMessages())
+    }
+}
+
+func TestExceptionImmutable(t *testing.T) {
+    before := ErrExceptionNetwork.StackMessages()
+    a := ErrExceptionNetwork.WithT("a")
+    b := ErrExceptionNetwork.WithT("b")
+    if a.StackMessages() != "Network error\na" || b.StackMessages() != "Network error\nb" {
+        t.Errorf("a=%q b=%q", a.StackMessages(), b.StackMessages())
+    }
+    if ErrExceptionNetwork.StackMessages() != before || ErrExceptionNetwork.IsT("a") {
+        t.Errorf("哨兵被修改: %q", ErrExceptionNetwork.StackMessages())
+    }
+    // 共享前缀的两个分支互不影响
+    base := NewExceptionT("base").WithT("x")
+    c1 := base.WithT("c1")
+    c2 := base.WithT("c2")
+    if c1.IsT("c2") || c2.IsT("c1") || base.IsT("c1") {
+        t.Errorf("c1=%q c2=%q", c1.StackMessages(), c2.StackMessages())
+    }
+    if f := base.WithField("k", 1); len(base.Fields()) != 0 || len(f.Fields()) != 1 {
+        t.Errorf("WithField 修改了原值")
+    }
+}
+
+// 配合 go test -race 运行
+func TestExceptionSentinelConcurrent(t *testing.T) {
+    sentinels := []Exception{ErrExceptionNetwork, ErrExceptionTimeout, ErrExceptionNotFound, ErrExceptionInvalidArgs}
+    before := make([]string, len(sentinels))
+    for i, s := range sentinels {
+        before[i] = JsonMarshal(s)
+    }
+    var wg sync.WaitGroup
+    for g := 0; g < 16; g++ {
+        wg.Add(1)
+        go func(g int) {
+            defer wg.Done()
+            for i := 0; i < 200; i++ {
+                for _, s := range sentinels {
+                    ex := s.With(fmt.Errorf("g%d-%d", g, i)).WithT("t").WithField("g", g).WithCode("C").WithStatus(500)
+                    if !errors.Is(ex, s) || ex.Dropped() != 0 {
+                        t.Errorf("链接结果错误: %q", ex.StackMessages())
+                        return
+                    }
+                    _ = fmt.Sprintf("%+v", s)
+                }
+            }
+        }(g)
+    }
+    wg.Wait()
+    for i, s := range sentinels {
+        if got := JsonMarshal(s); got != before[i] {
+            t.Errorf("哨兵被修改: %s, 原值 %s", got, before[i])
+        }
+    }
+}
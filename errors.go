@@ -0,0 +1,89 @@
+package rr
+
+import (
+    "strconv"
+    "strings"
+    "sync"
+)
+
+// 多错误聚合, 并发安全, 零值可用
+// 实现 Unwrap() []error, errors.Is / errors.As 会依次检查每一项
+type Errors struct {
+    mu   sync.Mutex
+    list []error
+}
+
+// 追加错误, 忽略 nil; 追加的 *Errors 会被展开
+func (r *Errors) Append(errs ...error) *Errors {
+    flat := make([]error, 0, len(errs))
+    for _, err := range errs {
+        if err == nil {
+            continue
+        }
+        if e, ok := err.(*Errors); ok {
+            if e != r {
+                flat = append(flat, e.Errors()...)
+            }
+            continue
+        }
+        flat = append(flat, err)
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.list = append(r.list, flat...)
+    return r
+}
+func (r *Errors) Len() int {
+    if r == nil {
+        return 0
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return len(r.list)
+}
+
+// 返回所有错误的副本
+func (r *Errors) Errors() []error {
+    if r == nil {
+        return nil
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return append([]error(nil), r.list...)
+}
+
+// 没有错误时返回 nil, 否则返回当前内容的快照
+func (r *Errors) ErrorOrNil() error {
+    if r == nil {
+        return nil
+    }
+    list := r.Errors()
+    if len(list) == 0 {
+        return nil
+    }
+    return &Errors{list: list}
+}
+func (r *Errors) Unwrap() []error {
+    return r.Errors()
+}
+
+// 单个错误直接输出, 多个错误带序号逐行输出
+func (r *Errors) Error() string {
+    list := r.Errors()
+    switch len(list) {
+    case 0:
+        return ""
+    case 1:
+        return list[0].Error()
+    }
+    var sb strings.Builder
+    sb.WriteString(strconv.Itoa(len(list)))
+    sb.WriteString(" errors occurred:")
+    for i, err := range list {
+        sb.WriteString("\n  [")
+        sb.WriteString(strconv.Itoa(i))
+        sb.WriteString("] ")
+        sb.WriteString(strings.ReplaceAll(err.Error(), "\n", "\n      "))
+    }
+    return sb.String()
+}
@@ -0,0 +1,102 @@
+package rr
+
+import (
+    "context"
+    "errors"
+    "io/fs"
+    "sync"
+    "testing"
+)
+
+func TestErrors(t *testing.T) {
+    var errs Errors
+    if errs.ErrorOrNil() != nil || errs.Len() != 0 {
+        t.Fatalf("零值应无错误")
+    }
+    pathErr := &fs.PathError{Op: "open", Path: "a", Err: fs.ErrNotExist}
+    errs.Append(nil, errors.New("first"), ErrExceptionTimeout.WithT("call api\nretry later"), nil)
+    errs.Append(new(Errors).Append(pathErr))
+    if errs.Len() != 3 {
+        t.Fatalf("Len() = %d, 期望 3", errs.Len())
+    }
+    err := errs.ErrorOrNil()
+    if !errors.Is(err, ErrExceptionTimeout) || !errors.Is(err, fs.ErrNotExist) {
+        t.Errorf("errors.Is 应检查每一项")
+    }
+    var got *fs.PathError
+    if !errors.As(err, &got) || got != pathErr {
+        t.Errorf("errors.As 未取回 *fs.PathError")
+    }
+    want := "3 errors occurred:\n  [0] first\n  [1] Timeout\n  [2] open a: file does not exist"
+    if err.Error() != want {
+        t.Errorf("Error() = %q\n期望 %q", err.Error(), want)
+    }
+    // 快照不受后续追加影响
+    errs.Append(errors.New("later"))
+    if err.(*Errors).Len() != 3 {
+        t.Errorf("ErrorOrNil 应返回快照")
+    }
+    single := new(Errors).Append(errors.New("only"))
+    if single.Error() != "only" {
+        t.Errorf("单个错误 Error() = %q", single.Error())
+    }
+}
+
+func TestErrorsConcurrentHelpers(t *testing.T) {
+    e1, e2 := errors.New("e1"), errors.New("e2")
+    ok := func() error { return nil }
+
+    calls := 0
+    err := CatchAll(func() error { calls++; return e1 }, ok, func() error { calls++; return e2 })
+    if calls != 2 || !errors.Is(err, e1) || !errors.Is(err, e2) {
+        t.Errorf("CatchAll err=%v calls=%d", err, calls)
+    }
+    if CatchAll(ok, ok) != nil {
+        t.Errorf("CatchAll 全部成功应返回 nil")
+    }
+
+    err = AsyncWaitAll(
+        Async(context.Background(), func(ctx context.Context) error { return e1 }),
+        Async(context.Background(), func(ctx context.Context) error { return nil }),
+        Async(context.Background(), func(ctx context.Context) error { return e2 }),
+    )
+    if err.(*Errors).Len() != 2 || !errors.Is(err, e2) {
+        t.Errorf("AsyncWaitAll err=%v", err)
+    }
+
+    var mu sync.Mutex
+    running, peak := 0, 0
+    fns := make([]func() error, 20)
+    for i := range fns {
+        i := i
+        fns[i] = func() error {
+            mu.Lock()
+            running++
+            peak = max(peak, running)
+            mu.Unlock()
+            defer func() {
+                mu.Lock()
+                running--
+                mu.Unlock()
+            }()
+            if i%5 == 0 {
+                return e1
+            }
+            return nil
+        }
+    }
+    err = CCRun(3, fns...)
+    if err.(*Errors).Len() != 4 || peak > 3 {
+        t.Errorf("CCRun err=%v peak=%d", err, peak)
+    }
+
+    // 不限制并发, panic 转换为错误
+    err = CCRun(0, func() error {
+        panic("boom")
+    }, func() error {
+        return e1
+    })
+    if !errors.Is(err, ErrExceptionPanic) || !errors.Is(err, e1) {
+        t.Errorf("CCRun(0) err=%v", err)
+    }
+}
@@ -0,0 +1,320 @@
+package rr
+
+import (
+    "context"
+    "strings"
+    "sync"
+    "sync/atomic"
+)
+
+var (
+    ErrEventBusClosed    = newBuiltinException("Event bus is closed", "EVENT_BUS_CLOSED", 503, CanonicalUnavailable)
+    ErrEventQueueFull    = newBuiltinException("Event subscriber queue is full", "EVENT_QUEUE_FULL", 503, CanonicalResourceExhausted)
+    ErrEventInvalidTopic = newBuiltinException("Invalid event topic", "EVENT_INVALID_TOPIC", 400, CanonicalInvalidArgument)
+)
+
+// 事件, Topic 为发布时的主题
+type Event[T any] struct {
+    Topic   string
+    Payload T
+}
+
+type EventHandler[T any] func(ctx context.Context, e Event[T]) error
+
+// 异步订阅者队列已满时的处理方式
+type OverflowPolicy int
+
+const (
+    // 阻塞发布方直到队列有空位或发布方 ctx 结束
+    OverflowBlock OverflowPolicy = iota
+    // 丢弃新事件
+    OverflowDropNewest
+    // 丢弃队列中最旧的事件
+    OverflowDropOldest
+    // 丢弃新事件, Publish 返回 ErrEventQueueFull
+    OverflowError
+)
+
+// 进程内的发布订阅
+// 主题按 "." 分段, 订阅时 "*" 匹配一段, "#" 匹配任意多段(含零段)
+type EventBus[T any] interface {
+    // 订阅 pattern, 默认同步执行
+    Subscribe(pattern string, handler EventHandler[T], opts ...SubscribeOption) (Subscription, error)
+    // 发布事件, 按订阅顺序投递
+    // 同步订阅者在发布方 goroutine 中执行, 其错误(含 panic)与异步订阅者的入队错误一起以 *Errors 返回
+    Publish(ctx context.Context, topic string, payload T) error
+    // 停止接收新事件并等待异步订阅者处理完队列中的事件, ctx 结束时返回 ctx.Err()
+    Close(ctx context.Context) error
+}
+
+type Subscription interface {
+    // 取消订阅, 已入队的事件仍会被处理
+    Unsubscribe()
+    // 因队列已满被丢弃的事件数
+    Dropped() int64
+}
+
+type subscribeOptions struct {
+    async    bool
+    buffer   int
+    overflow OverflowPolicy
+}
+
+type SubscribeOption func(*subscribeOptions)
+
+// 在独立的 goroutine 中按顺序处理事件, 队列容量为 buffer
+func SubscribeAsync(buffer int) SubscribeOption {
+    return func(r *subscribeOptions) {
+        r.async = true
+        r.buffer = max(buffer, 0)
+    }
+}
+
+// 异步订阅者队列已满时的处理方式, 默认 OverflowBlock
+func SubscribeOverflow(p OverflowPolicy) SubscribeOption {
+    return func(r *subscribeOptions) {
+        r.overflow = p
+    }
+}
+
+type eventBusOptions struct {
+    onError func(topic string, err error)
+}
+
+type EventBusOption func(*eventBusOptions)
+
+// 异步订阅者返回错误或 panic 时的回调
+func EventBusOnError(f func(topic string, err error)) EventBusOption {
+    return func(r *eventBusOptions) {
+        r.onError = f
+    }
+}
+
+type eventBus[T any] struct {
+    opts   eventBusOptions
+    mu     sync.RWMutex
+    subs   []*eventSub[T]
+    closed bool
+    wg     sync.WaitGroup
+}
+
+func NewEventBus[T any](opts ...EventBusOption) EventBus[T] {
+    r := &eventBus[T]{}
+    for _, opt := range opts {
+        opt(&r.opts)
+    }
+    return r
+}
+
+type eventDelivery[T any] struct {
+    ctx   context.Context
+    event Event[T]
+}
+
+type eventSub[T any] struct {
+    bus      *eventBus[T]
+    pattern  []string
+    handler  EventHandler[T]
+    opts     subscribeOptions
+    dropped  atomic.Int64
+    queue    chan eventDelivery[T]
+    mu       sync.RWMutex
+    closed   bool
+    quit     chan struct{}
+    quitOnce sync.Once
+}
+
+func (r *eventBus[T]) Subscribe(pattern string, handler EventHandler[T], opts ...SubscribeOption) (Subscription, error) {
+    if pattern == "" || handler == nil {
+        return nil, ErrEventInvalidTopic.WithT("EventBus: empty pattern or nil handler")
+    }
+    s := &eventSub[T]{
+        bus:     r,
+        pattern: strings.Split(pattern, "."),
+        handler: handler,
+        quit:    make(chan struct{}),
+    }
+    for _, opt := range opts {
+        opt(&s.opts)
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if r.closed {
+        return nil, ErrEventBusClosed
+    }
+    if s.opts.async {
+        s.queue = make(chan eventDelivery[T], s.opts.buffer)
+        r.wg.Add(1)
+        go s.loop()
+    }
+    r.subs = append(r.subs, s)
+    return s, nil
+}
+
+func (r *eventBus[T]) Publish(ctx context.Context, topic string, payload T) error {
+    r.mu.RLock()
+    if r.closed {
+        r.mu.RUnlock()
+        return ErrEventBusClosed
+    }
+    segments := strings.Split(topic, ".")
+    var matched []*eventSub[T]
+    for _, s := range r.subs {
+        if topicMatch(s.pattern, segments) {
+            matched = append(matched, s)
+        }
+    }
+    r.mu.RUnlock()
+
+    var errs Errors
+    event := Event[T]{Topic: topic, Payload: payload}
+    for _, s := range matched {
+        if s.opts.async {
+            errs.Append(s.enqueue(ctx, event))
+        } else {
+            errs.Append(s.call(ctx, event))
+        }
+    }
+    return errs.ErrorOrNil()
+}
+
+func (r *eventBus[T]) Close(ctx context.Context) error {
+    r.mu.Lock()
+    subs := r.subs
+    r.subs = nil
+    r.closed = true
+    r.mu.Unlock()
+    for _, s := range subs {
+        s.close()
+    }
+    done := make(chan struct{})
+    go func() {
+        r.wg.Wait()
+        close(done)
+    }()
+    select {
+    case <-done:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+func (r *eventBus[T]) remove(s *eventSub[T]) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    for i, x := range r.subs {
+        if x == s {
+            r.subs = append(r.subs[:i:i], r.subs[i+1:]...)
+            return
+        }
+    }
+}
+
+func (s *eventSub[T]) Unsubscribe() {
+    s.bus.remove(s)
+    s.close()
+}
+
+func (s *eventSub[T]) Dropped() int64 {
+    return s.dropped.Load()
+}
+
+// 执行 handler, panic 转换为错误
+func (s *eventSub[T]) call(ctx context.Context, e Event[T]) (err error) {
+    defer func() {
+        if r := recover(); r != nil {
+            err = NewPanicException("event handler panicked", r)
+        }
+    }()
+    return s.handler(ctx, e)
+}
+
+func (s *eventSub[T]) enqueue(ctx context.Context, e Event[T]) error {
+    d := eventDelivery[T]{ctx: context.WithoutCancel(ctx), event: e}
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    if s.closed {
+        return nil
+    }
+    select {
+    case s.queue <- d:
+        return nil
+    default:
+    }
+    switch s.opts.overflow {
+    case OverflowDropOldest:
+        if cap(s.queue) > 0 {
+            for {
+                select {
+                case <-s.queue:
+                    s.dropped.Add(1)
+                default:
+                }
+                select {
+                case s.queue <- d:
+                    return nil
+                default:
+                }
+            }
+        }
+        // 无缓冲的队列没有可丢弃的旧事件
+        s.dropped.Add(1)
+        return nil
+    case OverflowDropNewest:
+        s.dropped.Add(1)
+        return nil
+    case OverflowError:
+        s.dropped.Add(1)
+        return ErrEventQueueFull.WithT(e.Topic)
+    }
+    select {
+    case s.queue <- d:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    case <-s.quit:
+        return nil
+    }
+}
+
+func (s *eventSub[T]) loop() {
+    defer s.bus.wg.Done()
+    for d := range s.queue {
+        if err := s.call(d.ctx, d.event); err != nil && s.bus.opts.onError != nil {
+            s.bus.opts.onError(d.event.Topic, err)
+        }
+    }
+}
+
+// 停止接收事件, 异步订阅者处理完队列后退出
+func (s *eventSub[T]) close() {
+    s.quitOnce.Do(func() {
+        close(s.quit)
+        s.mu.Lock()
+        s.closed = true
+        if s.queue != nil {
+            close(s.queue)
+        }
+        s.mu.Unlock()
+    })
+}
+
+// pattern 与 topic 的分段是否匹配
+func topicMatch(pattern, topic []string) bool {
+    if len(pattern) == 0 {
+        return len(topic) == 0
+    }
+    switch pattern[0] {
+    case "#":
+        for i := 0; i <= len(topic); i++ {
+            if topicMatch(pattern[1:], topic[i:]) {
+                return true
+            }
+        }
+        return false
+    case "*":
+        return len(topic) > 0 && topicMatch(pattern[1:], topic[1:])
+    }
+    return len(topic) > 0 && pattern[0] == topic[0] && topicMatch(pattern[1:], topic[1:])
+}
@@ -0,0 +1,182 @@
+package rr
+
+import (
+    "context"
+    "errors"
+    "reflect"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+func TestTopicMatch(t *testing.T) {
+    cases := []struct {
+        pattern, topic string
+        want           bool
+    }{
+        {"order.created", "order.created", true},
+        {"order.created", "order.paid", false},
+        {"order.*", "order.paid", true},
+        {"order.*", "order.paid.ok", false},
+        {"order.#", "order", true},
+        {"order.#", "order.paid.ok", true},
+        {"#.ok", "order.paid.ok", true},
+        {"*.*.ok", "order.ok", false},
+        {"#", "anything.at.all", true},
+    }
+    for _, c := range cases {
+        if got := topicMatch(strings.Split(c.pattern, "."), strings.Split(c.topic, ".")); got != c.want {
+            t.Errorf("%s ~ %s = %v", c.pattern, c.topic, got)
+        }
+    }
+}
+
+func TestEventBusSync(t *testing.T) {
+    bus := NewEventBus[int]()
+    var got []string
+    bus.Subscribe("order.*", func(ctx context.Context, e Event[int]) error {
+        got = append(got, e.Topic)
+        return nil
+    })
+    fail := errors.New("fail")
+    sub, _ := bus.Subscribe("order.paid", func(ctx context.Context, e Event[int]) error {
+        return fail
+    })
+    bus.Subscribe("order.#", func(ctx context.Context, e Event[int]) error {
+        panic("boom")
+    })
+
+    err := bus.Publish(context.Background(), "order.paid", 1)
+    var errs *Errors
+    if !errors.As(err, &errs) || errs.Len() != 2 || !errors.Is(err, fail) || !errors.Is(err, ErrExceptionPanic) {
+        t.Fatalf("err = %v", err)
+    }
+    if !reflect.DeepEqual(got, []string{"order.paid"}) {
+        t.Errorf("panic 不应影响其它订阅者, got = %v", got)
+    }
+
+    sub.Unsubscribe()
+    sub.Unsubscribe()
+    err = bus.Publish(context.Background(), "order.paid", 2)
+    if errors.Is(err, fail) {
+        t.Errorf("取消订阅后不应再收到事件, err = %v", err)
+    }
+    if _, err := bus.Subscribe("", nil); !errors.Is(err, ErrEventInvalidTopic) {
+        t.Errorf("err = %v", err)
+    }
+    bus.Close(context.Background())
+    if err := bus.Publish(context.Background(), "order.paid", 3); !errors.Is(err, ErrEventBusClosed) {
+        t.Errorf("err = %v", err)
+    }
+}
+
+func TestEventBusAsync(t *testing.T) {
+    var mu sync.Mutex
+    var handlerErrs []error
+    bus := NewEventBus[int](EventBusOnError(func(topic string, err error) {
+        mu.Lock()
+        handlerErrs = append(handlerErrs, err)
+        mu.Unlock()
+    }))
+    release := make(chan struct{})
+    var got []int
+    bus.Subscribe("jobs", func(ctx context.Context, e Event[int]) error {
+        <-release
+        got = append(got, e.Payload)
+        if e.Payload == 3 {
+            panic("boom")
+        }
+        return nil
+    }, SubscribeAsync(10))
+    for i := 1; i <= 3; i++ {
+        if err := bus.Publish(context.Background(), "jobs", i); err != nil {
+            t.Fatal(err)
+        }
+    }
+    close(release)
+    // Close 等待队列中的事件处理完
+    if err := bus.Close(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(got, []int{1, 2, 3}) {
+        t.Errorf("got = %v", got)
+    }
+    if len(handlerErrs) != 1 || !errors.Is(handlerErrs[0], ErrExceptionPanic) {
+        t.Errorf("errs = %v", handlerErrs)
+    }
+}
+
+func TestEventBusOverflow(t *testing.T) {
+    bus := NewEventBus[int]()
+    release := make(chan struct{})
+    started := make(chan struct{}, 10)
+    var mu sync.Mutex
+    got := map[string][]int{}
+    handler := func(ctx context.Context, e Event[int]) error {
+        started <- struct{}{}
+        <-release
+        mu.Lock()
+        got[e.Topic] = append(got[e.Topic], e.Payload)
+        mu.Unlock()
+        return nil
+    }
+    newest, _ := bus.Subscribe("newest", handler, SubscribeAsync(1), SubscribeOverflow(OverflowDropNewest))
+    oldest, _ := bus.Subscribe("oldest", handler, SubscribeAsync(1), SubscribeOverflow(OverflowDropOldest))
+    bus.Subscribe("error", handler, SubscribeAsync(1), SubscribeOverflow(OverflowError))
+    bus.Subscribe("block", handler, SubscribeAsync(1))
+
+    for _, topic := range []string{"newest", "oldest", "error", "block"} {
+        // 第一个事件被取走处理, 第二个进入队列, 队列已满
+        bus.Publish(context.Background(), topic, 1)
+        <-started
+        bus.Publish(context.Background(), topic, 2)
+    }
+    bus.Publish(context.Background(), "newest", 3)
+    bus.Publish(context.Background(), "oldest", 3)
+    if err := bus.Publish(context.Background(), "error", 3); !errors.Is(err, ErrEventQueueFull) {
+        t.Errorf("err = %v", err)
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    defer cancel()
+    if err := bus.Publish(ctx, "block", 3); !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("err = %v", err)
+    }
+
+    close(release)
+    bus.Close(context.Background())
+    want := map[string][]int{
+        "newest": {1, 2},
+        "oldest": {1, 3},
+        "error":  {1, 2},
+        "block":  {1, 2},
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("got = %v", got)
+    }
+    if newest.Dropped() != 1 || oldest.Dropped() != 1 {
+        t.Errorf("dropped = %d %d", newest.Dropped(), oldest.Dropped())
+    }
+}
+
+func TestEventBusCloseTimeout(t *testing.T) {
+    bus := NewEventBus[string]()
+    release := make(chan struct{})
+    bus.Subscribe("x", func(ctx context.Context, e Event[string]) error {
+        <-release
+        return nil
+    }, SubscribeAsync(1))
+    bus.Publish(context.Background(), "x", "a")
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    defer cancel()
+    if err := bus.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("err = %v", err)
+    }
+    close(release)
+    if err := bus.Close(context.Background()); err != nil {
+        t.Errorf("err = %v", err)
+    }
+    if _, err := bus.Subscribe("x", func(ctx context.Context, e Event[string]) error { return nil }); !errors.Is(err, ErrEventBusClosed) {
+        t.Errorf("err = %v", err)
+    }
+}
@@ -116,11 +116,10 @@ func demo2() {
 }
 
 // 示例3：Async + 主动取消（任务内遵循 ctx）
-// 说明：Async 本身不会自动感知 ctx，需要在任务函数中自己 select ctx.Done。
+// 说明：每个任务持有自己的 ctx，调用 Cancel() 即可单独取消，任务函数中需自己 select ctx.Done。
 func demo3() {
     log.Println("示例3：Async + 主动取消（任务内遵循 ctx）")
-    ctx3, cancel3 := context.WithCancel(context.Background())
-    a3 := rr.Async(ctx3, func(ctx context.Context) error {
+    a3 := rr.Async(context.Background(), func(ctx context.Context) error {
         ticker := time.NewTicker(300 * time.Millisecond)
         defer ticker.Stop()
         for i := 0; i < 10; i++ {
@@ -135,8 +134,9 @@ func demo3() {
     })
     // 模拟工作一段时间后取消
     time.Sleep(900 * time.Millisecond)
-    cancel3()
-    log.Println("取消结果:", a3.Get())
+    a3.Cancel()
+    err := a3.Get()
+    log.Println("取消结果:", err, " 是否为取消:", errors.Is(err, rr.ErrTaskCancelled))
 }
 
 // 示例4：AsyncResult 基本用法（获取返回值）
@@ -198,15 +198,17 @@ func demo6() {
             return 3, nil
         }),
     }
+    // WhenAllSettled 等待全部完成并按任务顺序返回每个任务的值与错误
+    // 只关心全部成功时可用 rr.WhenAll，它会在首个错误时立即返回
+    results, _ := rr.WhenAllSettled(context.Background(), tasks...)
     sum := 0
     var firstErr error
-    for idx, t := range tasks {
-        v, err := t.Get()
-        if err != nil && firstErr == nil {
-            firstErr = err
+    for idx, r := range results {
+        if r.Err != nil && firstErr == nil {
+            firstErr = r.Err
         }
-        log.Println("任务", idx, "完成: 值=", v, " 错误=", err)
-        sum += v
+        log.Println("任务", idx, "完成: 值=", r.Value, " 错误=", r.Err)
+        sum += r.Value
     }
     log.Println("聚合结果: sum=", sum, " 首个错误=", firstErr)
 }
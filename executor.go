@@ -0,0 +1,382 @@
+package rr
+
+import (
+    "context"
+    "runtime"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+
+var (
+    ErrExecutorRejected = newBuiltinException("Executor rejected task", "EXECUTOR_REJECTED", 503, CanonicalResourceExhausted)
+    ErrExecutorShutdown = newBuiltinException("Executor is shut down", "EXECUTOR_SHUTDOWN", 503, CanonicalUnavailable)
+)
+
+// 队列已满且线程数已达上限时的拒绝策略
+type RejectPolicy int
+
+const (
+    // 阻塞等待队列空位, 直到提交方 ctx 结束或执行器关闭
+    RejectBlock RejectPolicy = iota
+    // 丢弃任务, 返回的任务直接以 ErrExecutorRejected 结束
+    RejectDrop
+    // 在提交方的 goroutine 中同步执行
+    RejectCallerRuns
+    // Submit 返回 ErrExecutorRejected
+    RejectError
+)
+
+// 执行器统计, Completed 为成功结束的任务数, Failed 为返回错误或 panic 的任务数
+type ExecutorStats struct {
+    Workers   int
+    Queued    int64
+    Running   int64
+    Completed int64
+    Failed    int64
+    Rejected  int64
+}
+
+// 有界工作池
+// 常驻 min 个 worker, 队列满时扩容到 max 个, 空闲超过 keepAlive 的非常驻 worker 会退出
+type Executor interface {
+    // 提交任务, 返回的任务支持 Cancel/Wait 等操作; 排队期间被取消的任务不会执行
+    Submit(ctx context.Context, fn func(ctx context.Context) error) (AsyncTask, error)
+    // 停止接收新任务, 等待队列中与执行中的任务完成; ctx 结束时返回 ctx.Err(), 剩余任务仍会在后台执行完
+    Shutdown(ctx context.Context) error
+    Stats() ExecutorStats
+}
+
+type executorJob struct {
+    task *taskCore
+    run  func()
+}
+
+type executor struct {
+    min       int
+    max       int
+    keepAlive time.Duration
+    policy    RejectPolicy
+    queue     chan executorJob
+
+    mu       sync.RWMutex
+    workers  int
+    shutdown bool
+    quit     chan struct{}
+    stopped  chan struct{}
+    quitOnce sync.Once
+    wg       sync.WaitGroup
+
+    running   atomic.Int64
+    completed atomic.Int64
+    failed    atomic.Int64
+    rejected  atomic.Int64
+}
+
+type ExecutorOption func(*executor)
+
+// worker 数量, min == max 时为固定大小, 默认均为 runtime.NumCPU()
+func ExecutorWorkers(min, max int) ExecutorOption {
+    return func(r *executor) {
+        if min < 0 {
+            min = 0
+        }
+        if max < 1 {
+            max = 1
+        }
+        if min > max {
+            min = max
+        }
+        r.min, r.max = min, max
+    }
+}
+
+// 队列容量, 默认 1024
+func ExecutorQueueSize(n int) ExecutorOption {
+    return func(r *executor) {
+        if n >= 0 {
+            r.queue = make(chan executorJob, n)
+        }
+    }
+}
+
+// 非常驻 worker 的空闲存活时间, 默认 60s
+func ExecutorKeepAlive(d time.Duration) ExecutorOption {
+    return func(r *executor) {
+        if d > 0 {
+            r.keepAlive = d
+        }
+    }
+}
+
+// 拒绝策略, 默认 RejectBlock
+func ExecutorRejectPolicy(p RejectPolicy) ExecutorOption {
+    return func(r *executor) {
+        r.policy = p
+    }
+}
+
+func NewExecutor(opts ...ExecutorOption) Executor {
+    n := runtime.NumCPU()
+    r := &executor{
+        min:       n,
+        max:       n,
+        keepAlive: 60 * time.Second,
+        queue:     make(chan executorJob, 1024),
+        quit:      make(chan struct{}),
+        stopped:   make(chan struct{}),
+    }
+    for _, opt := range opts {
+        opt(r)
+    }
+    r.mu.Lock()
+    for i := 0; i < r.min; i++ {
+        r.startWorker(executorJob{})
+    }
+    r.mu.Unlock()
+    return r
+}
+
+func (r *executor) Submit(ctx context.Context, fn func(ctx context.Context) error) (AsyncTask, error) {
+    task := newAsync(ctx)
+    err := r.submit(ctx, &task.taskCore, func() {
+        task.run("async task panicked", fn)
+    })
+    if err != nil {
+        return nil, err
+    }
+    return task, nil
+}
+
+// 提交带返回值的任务, e 不是 NewExecutor 创建的执行器时通过其 Submit 执行
+func SubmitResult[T any](e Executor, ctx context.Context, fn func(ctx context.Context) (T, error)) (AsyncResultTask[T], error) {
+    task := newAsyncResult[T](ctx)
+    run := func() {
+        task.runResult("async result task panicked", fn)
+    }
+    if r, ok := e.(*executor); ok {
+        if err := r.submit(ctx, &task.taskCore, run); err != nil {
+            return nil, err
+        }
+        return task, nil
+    }
+    inner, err := e.Submit(ctx, func(ctx context.Context) error {
+        run()
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    // 未执行就结束(被丢弃或取消)时以其错误结束
+    go func() {
+        if err := inner.Wait(context.Background()); err != nil {
+            task.finish(err)
+        }
+    }()
+    return task, nil
+}
+
+func (r *executor) submit(ctx context.Context, task *taskCore, run func()) error {
+    job := executorJob{task: task, run: run}
+    r.mu.RLock()
+    if r.shutdown {
+        r.mu.RUnlock()
+        return ErrExecutorShutdown
+    }
+    if r.enqueue(job) {
+        idle := r.workers == 0
+        r.mu.RUnlock()
+        if idle {
+            r.ensureWorker()
+        }
+        return nil
+    }
+    r.mu.RUnlock()
+
+    r.mu.Lock()
+    if r.shutdown {
+        r.mu.Unlock()
+        return ErrExecutorShutdown
+    }
+    // 再试一次, 期间可能已有空位
+    if r.enqueue(job) {
+        if r.workers == 0 {
+            r.startWorker(executorJob{})
+        }
+        r.mu.Unlock()
+        return nil
+    }
+    if r.workers < r.max {
+        r.startWorker(job)
+        r.mu.Unlock()
+        return nil
+    }
+    r.mu.Unlock()
+
+    switch r.policy {
+    case RejectDrop:
+        r.rejected.Add(1)
+        task.finish(ErrExecutorRejected)
+        return nil
+    case RejectCallerRuns:
+        r.exec(job)
+        return nil
+    case RejectError:
+        r.rejected.Add(1)
+        return ErrExecutorRejected
+    }
+    r.mu.RLock()
+    if r.shutdown {
+        r.mu.RUnlock()
+        return ErrExecutorShutdown
+    }
+    select {
+    case r.queue <- job:
+        idle := r.workers == 0
+        r.mu.RUnlock()
+        if idle {
+            r.ensureWorker()
+        }
+        return nil
+    case <-ctx.Done():
+        r.mu.RUnlock()
+        return ctx.Err()
+    case <-r.quit:
+        r.mu.RUnlock()
+        return ErrExecutorShutdown
+    }
+}
+
+// 队列中有任务但没有 worker 时启动一个, min 为 0 或最后一个 worker 刚退出时队列中的任务不会滞留
+func (r *executor) ensureWorker() {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if r.workers == 0 && len(r.queue) > 0 {
+        r.startWorker(executorJob{})
+    }
+}
+
+// 非阻塞入队, 调用方需持有锁
+func (r *executor) enqueue(job executorJob) bool {
+    select {
+    case r.queue <- job:
+        return true
+    default:
+        return false
+    }
+}
+
+// 调用方需持有写锁
+func (r *executor) startWorker(first executorJob) {
+    r.workers++
+    r.wg.Add(1)
+    go r.worker(first)
+}
+
+func (r *executor) worker(first executorJob) {
+    defer r.wg.Done()
+    if first.run != nil {
+        r.exec(first)
+    }
+    var idle *time.Timer
+    for {
+        select {
+        case job := <-r.queue:
+            r.exec(job)
+            continue
+        default:
+        }
+        var idleC <-chan time.Time
+        if r.min < r.max {
+            if idle == nil {
+                idle = time.NewTimer(r.keepAlive)
+                defer idle.Stop()
+            } else {
+                idle.Reset(r.keepAlive)
+            }
+            idleC = idle.C
+        }
+        select {
+        case job := <-r.queue:
+            if idle != nil && !idle.Stop() {
+                <-idle.C
+            }
+            r.exec(job)
+        case <-r.stopped:
+            for {
+                select {
+                case job := <-r.queue:
+                    r.exec(job)
+                default:
+                    r.retire(true)
+                    return
+                }
+            }
+        case <-idleC:
+            if r.retire(false) {
+                return
+            }
+        }
+    }
+}
+
+// 减少 worker 计数, 非强制时不会低于 min, 队列中还有任务时也不退出
+func (r *executor) retire(force bool) bool {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if !force && (r.shutdown || r.workers <= r.min || len(r.queue) > 0) {
+        return false
+    }
+    r.workers--
+    return true
+}
+
+func (r *executor) exec(job executorJob) {
+    r.running.Add(1)
+    job.run()
+    r.running.Add(-1)
+    if job.task.err != nil {
+        r.failed.Add(1)
+    } else {
+        r.completed.Add(1)
+    }
+}
+
+func (r *executor) Shutdown(ctx context.Context) error {
+    r.quitOnce.Do(func() {
+        close(r.quit)
+        r.mu.Lock()
+        r.shutdown = true
+        close(r.stopped)
+        // 没有 worker 时由新启动的 worker 处理完队列中的任务
+        if r.workers == 0 && len(r.queue) > 0 {
+            r.startWorker(executorJob{})
+        }
+        r.mu.Unlock()
+    })
+    done := make(chan struct{})
+    go func() {
+        r.wg.Wait()
+        close(done)
+    }()
+    select {
+    case <-done:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+func (r *executor) Stats() ExecutorStats {
+    r.mu.RLock()
+    workers := r.workers
+    r.mu.RUnlock()
+    return ExecutorStats{
+        Workers:   workers,
+        Queued:    int64(len(r.queue)),
+        Running:   r.running.Load(),
+        Completed: r.completed.Load(),
+        Failed:    r.failed.Load(),
+        Rejected:  r.rejected.Load(),
+    }
+}
@@ -0,0 +1,258 @@
+package rr
+
+import (
+    "context"
+    "errors"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+func TestExecutorSubmit(t *testing.T) {
+    e := NewExecutor(ExecutorWorkers(2, 2))
+    var n atomic.Int32
+    tasks := make([]AsyncTask, 0, 10)
+    for i := 0; i < 10; i++ {
+        task, err := e.Submit(context.Background(), func(ctx context.Context) error {
+            n.Add(1)
+            return nil
+        })
+        if err != nil {
+            t.Fatal(err)
+        }
+        tasks = append(tasks, task)
+    }
+    if err := AsyncWaitAll(tasks...); err != nil || n.Load() != 10 {
+        t.Fatalf("n=%d err=%v", n.Load(), err)
+    }
+
+    r, err := SubmitResult(e, context.Background(), func(ctx context.Context) (int, error) {
+        return 42, nil
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if v, err := r.Get(); v != 42 || err != nil {
+        t.Errorf("v=%v err=%v", v, err)
+    }
+
+    failed, _ := e.Submit(context.Background(), func(ctx context.Context) error {
+        panic("boom")
+    })
+    if err := failed.Wait(context.Background()); !errors.Is(err, ErrExceptionPanic) {
+        t.Errorf("panic 应转换为错误, err = %v", err)
+    }
+
+    if err := e.Shutdown(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    s := e.Stats()
+    if s.Completed != 11 || s.Failed != 1 || s.Workers != 0 || s.Queued != 0 || s.Running != 0 {
+        t.Errorf("stats = %+v", s)
+    }
+    if _, err := e.Submit(context.Background(), func(ctx context.Context) error { return nil }); !errors.Is(err, ErrExecutorShutdown) {
+        t.Errorf("关闭后提交 err = %v", err)
+    }
+}
+
+func TestExecutorReject(t *testing.T) {
+    block := make(chan struct{})
+    wait := func(ctx context.Context) error {
+        <-block
+        return nil
+    }
+    newFull := func(p RejectPolicy) Executor {
+        e := NewExecutor(ExecutorWorkers(1, 1), ExecutorQueueSize(1), ExecutorRejectPolicy(p))
+        e.Submit(context.Background(), wait)
+        // 等待 worker 取走第一个任务后再占满队列
+        for e.Stats().Running != 1 {
+            time.Sleep(time.Millisecond)
+        }
+        e.Submit(context.Background(), wait)
+        return e
+    }
+
+    e := newFull(RejectError)
+    if _, err := e.Submit(context.Background(), wait); !errors.Is(err, ErrExecutorRejected) {
+        t.Errorf("RejectError err = %v", err)
+    }
+    if e.Stats().Rejected != 1 {
+        t.Errorf("stats = %+v", e.Stats())
+    }
+
+    d := newFull(RejectDrop)
+    task, err := d.Submit(context.Background(), wait)
+    if err != nil || !errors.Is(task.Wait(context.Background()), ErrExecutorRejected) {
+        t.Errorf("RejectDrop err = %v", err)
+    }
+
+    c := newFull(RejectCallerRuns)
+    ran := false
+    task, err = c.Submit(context.Background(), func(ctx context.Context) error {
+        ran = true
+        return nil
+    })
+    if err != nil || !ran || !task.IsDone() {
+        t.Errorf("RejectCallerRuns 应同步执行, err = %v", err)
+    }
+
+    b := newFull(RejectBlock)
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    defer cancel()
+    if _, err := b.Submit(ctx, wait); !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("RejectBlock err = %v", err)
+    }
+    // 阻塞中的提交方不计入 Queued
+    blocked := make(chan struct{})
+    go func() {
+        defer close(blocked)
+        b.Submit(context.Background(), wait)
+    }()
+    time.Sleep(10 * time.Millisecond)
+    if s := b.Stats(); s.Queued != 1 {
+        t.Errorf("stats = %+v", s)
+    }
+
+    close(block)
+    <-blocked
+    for _, x := range []Executor{e, d, c, b} {
+        if err := x.Shutdown(context.Background()); err != nil {
+            t.Error(err)
+        }
+    }
+}
+
+func TestExecutorElastic(t *testing.T) {
+    e := NewExecutor(ExecutorWorkers(0, 3), ExecutorQueueSize(0), ExecutorKeepAlive(10*time.Millisecond), ExecutorRejectPolicy(RejectError))
+    block := make(chan struct{})
+    for i := 0; i < 3; i++ {
+        if _, err := e.Submit(context.Background(), func(ctx context.Context) error {
+            <-block
+            return nil
+        }); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if s := e.Stats(); s.Workers != 3 {
+        t.Errorf("应扩容到 3 个 worker, stats = %+v", s)
+    }
+    if _, err := e.Submit(context.Background(), func(ctx context.Context) error { return nil }); !errors.Is(err, ErrExecutorRejected) {
+        t.Errorf("err = %v", err)
+    }
+    close(block)
+    deadline := time.Now().Add(time.Second)
+    for e.Stats().Workers != 0 && time.Now().Before(deadline) {
+        time.Sleep(5 * time.Millisecond)
+    }
+    if s := e.Stats(); s.Workers != 0 {
+        t.Errorf("空闲 worker 应退出, stats = %+v", s)
+    }
+    e.Shutdown(context.Background())
+}
+
+func TestExecutorElasticZeroMin(t *testing.T) {
+    e := NewExecutor(ExecutorWorkers(0, 2), ExecutorKeepAlive(10*time.Millisecond))
+    submit := func() {
+        ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+        defer cancel()
+        task, err := e.Submit(ctx, func(ctx context.Context) error { return nil })
+        if err != nil {
+            t.Fatal(err)
+        }
+        if err := task.Wait(ctx); err != nil {
+            t.Fatalf("队列未满时任务也应被执行, err=%v stats=%+v", err, e.Stats())
+        }
+    }
+    submit()
+    deadline := time.Now().Add(time.Second)
+    for e.Stats().Workers != 0 && time.Now().Before(deadline) {
+        time.Sleep(5 * time.Millisecond)
+    }
+    if s := e.Stats(); s.Workers != 0 {
+        t.Fatalf("空闲 worker 应退出, stats = %+v", s)
+    }
+    // 所有 worker 退出后提交的任务仍会被执行
+    submit()
+    e.Shutdown(context.Background())
+}
+
+func TestExecutorShutdownDrain(t *testing.T) {
+    e := NewExecutor(ExecutorWorkers(1, 1))
+    var n atomic.Int32
+    for i := 0; i < 5; i++ {
+        e.Submit(context.Background(), func(ctx context.Context) error {
+            time.Sleep(2 * time.Millisecond)
+            n.Add(1)
+            return nil
+        })
+    }
+    if err := e.Shutdown(context.Background()); err != nil || n.Load() != 5 {
+        t.Errorf("Shutdown 应执行完队列中的任务, n=%d err=%v", n.Load(), err)
+    }
+
+    slow := NewExecutor(ExecutorWorkers(1, 1))
+    release := make(chan struct{})
+    slow.Submit(context.Background(), func(ctx context.Context) error {
+        <-release
+        return nil
+    })
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    defer cancel()
+    if err := slow.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("err = %v", err)
+    }
+    close(release)
+    slow.Shutdown(context.Background())
+}
+
+// 包装后的执行器, 外部实现只需提供 Submit/Shutdown/Stats
+type wrappedExecutor struct {
+    e Executor
+}
+
+func (w wrappedExecutor) Submit(ctx context.Context, fn func(ctx context.Context) error) (AsyncTask, error) {
+    return w.e.Submit(ctx, fn)
+}
+func (w wrappedExecutor) Shutdown(ctx context.Context) error {
+    return w.e.Shutdown(ctx)
+}
+func (w wrappedExecutor) Stats() ExecutorStats {
+    return w.e.Stats()
+}
+
+func TestSubmitResultCustomExecutor(t *testing.T) {
+    inner := NewExecutor(ExecutorWorkers(1, 1), ExecutorQueueSize(1), ExecutorRejectPolicy(RejectDrop))
+    var e Executor = wrappedExecutor{e: inner}
+    r, err := SubmitResult(e, context.Background(), func(ctx context.Context) (int, error) {
+        return 42, nil
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if v, err := r.Get(); v != 42 || err != nil {
+        t.Errorf("v=%v err=%v", v, err)
+    }
+
+    // 外部执行器丢弃任务时结果任务也会结束
+    release := make(chan struct{})
+    defer inner.Shutdown(context.Background())
+    defer close(release)
+    wait := func(ctx context.Context) error {
+        <-release
+        return nil
+    }
+    inner.Submit(context.Background(), wait)
+    for inner.Stats().Running != 1 {
+        time.Sleep(time.Millisecond)
+    }
+    inner.Submit(context.Background(), wait)
+    dropped, err := SubmitResult(e, context.Background(), func(ctx context.Context) (int, error) {
+        return 1, nil
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := dropped.Get(); !errors.Is(err, ErrExecutorRejected) {
+        t.Errorf("err = %v", err)
+    }
+}
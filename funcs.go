@@ -1,6 +1,57 @@
 package rr
 
-import "time"
+import (
+    "errors"
+    "fmt"
+    "runtime/debug"
+    "time"
+)
+
+const (
+    ExceptionFieldPanic = "panic"
+    ExceptionFieldStack = "stack"
+)
+
+// 将 recover 得到的值转换为异常, 消息为 "prefix: 值"
+// 异常链包含 ErrExceptionPanic, 字段 panic 为 fmt.Sprint 后的值; 原始值为 error 时可通过 errors.Is/As 访问
+// 调用栈不放入字段, 不会出现在 JSON 中, 只在 %+v 与 LogValue 中输出
+func NewPanicException(prefix string, r any) Exception {
+    ex := NewExceptionT(fmt.Sprintf("%s: %v", prefix, r), ErrExceptionPanic).WithField(ExceptionFieldPanic, fmt.Sprint(r)).(*exceptionStack)
+    ex.stack = string(debug.Stack())
+    if err, ok := r.(error); ok {
+        return ex.With(err)
+    }
+    return ex
+}
+
+// 取出 panic 异常中的值, 为 fmt.Sprint 后的字符串
+func PanicValue(err error) (any, bool) {
+    var ex Exception
+    if !errors.Is(err, ErrExceptionPanic) || !errors.As(err, &ex) {
+        return nil, false
+    }
+    return ex.Field(ExceptionFieldPanic)
+}
+
+// 取出 panic 异常记录的调用栈
+func PanicStack(err error) (string, bool) {
+    var ex *exceptionStack
+    if !errors.As(err, &ex) {
+        return "", false
+    }
+    s := ex.panicStack()
+    return s, s != ""
+}
+
+// 执行 f, panic 转换为异常返回
+func callSafe(f func() error) (err error) {
+    defer func() {
+        if r := recover(); r != nil {
+            err = NewPanicException("panic", r)
+        }
+    }()
+    return f()
+}
 
 func Try(callable func() error, callables ...func() error) error {
     return Catch(callable, callables...)
@@ -8,37 +59,84 @@ func Try(callable func() error, callables ...func() error) error {
 func TryCatch(callable func() error, callables ...func() error) error {
     return Catch(callable, callables...)
 }
+
+// 依次执行, 返回第一个错误; panic 会被转换为异常返回
 func Catch(callable func() error, callables ...func() error) error {
-    err := callable()
+    err := callSafe(callable)
     if err != nil {
         return err
     }
     for _, f := range callables {
-        err = f()
+        err = callSafe(f)
         if err != nil {
             return err
         }
     }
     return nil
 }
-func Retry(count int, callable func() error) error {
-    var err error
-    for i := 0; i < count; i++ {
-        err = callable()
-        if err == nil {
-            return nil
+
+// 执行 try, 之后无论成功、失败或 panic 都依次执行 finally
+// panic 会被转换为异常; 只有一个错误时原样返回, 多个错误时返回 *Errors, 第一项为 try 的错误
+func TryFinally(try func() error, finally ...func() error) error {
+    var errs Errors
+    errs.Append(callSafe(try))
+    for _, f := range finally {
+        errs.Append(callSafe(f))
+    }
+    if list := errs.Errors(); len(list) == 1 {
+        return list[0]
+    }
+    return errs.ErrorOrNil()
+}
+
+// 同 TryFinally, try 失败时先交给 catch 处理, catch 返回 nil 表示错误已被处理
+func TryCatchFinally(try func() error, catch func(err error) error, finally ...func() error) error {
+    return TryFinally(func() error {
+        err := callSafe(try)
+        if err == nil || catch == nil {
+            return err
         }
+        return catch(err)
+    }, finally...)
+}
+
+// 依次执行所有 callable, 不因失败或 panic 中断, 返回聚合后的 *Errors 或 nil
+func CatchAll(callables ...func() error) error {
+    var errs Errors
+    for _, f := range callables {
+        errs.Append(callSafe(f))
     }
-    return err
+    return errs.ErrorOrNil()
 }
+
+// 重试 count 次, 错误分类与 RetryInterval 相同, RetryAfter 指定的等待时长会被遵守
+func Retry(count int, callable func() error) error {
+    return RetryInterval(count, 0, callable)
+}
+
+// 重试 count 次, 每次间隔 sleepFor
+// 错误分类与 RetryWithOptions 默认一致: Permanent 标记或 RetryPolicyException 判定为不可重试的错误立即停止, RetryAfter 指定的等待时长优先于 sleepFor
 func RetryInterval(count int, sleepFor time.Duration, callable func() error) error {
+    policy := RetryPolicyException()
     var err error
     for i := 0; i < count; i++ {
         err = callable()
         if err == nil {
             return nil
         }
-        time.Sleep(sleepFor)
+        if IsPermanent(err) {
+            break
+        }
+        delay, ok := RetryAfterDelay(err)
+        if !ok && !policy(unwrapRetryMark(err)) {
+            break
+        }
+        if !ok {
+            delay = sleepFor
+        }
+        if i < count-1 && delay > 0 {
+            time.Sleep(delay)
+        }
     }
-    return err
+    return unwrapRetryMark(err)
 }
@@ -0,0 +1,124 @@
+package rr
+
+import (
+    "errors"
+    "fmt"
+    "log/slog"
+    "strings"
+    "testing"
+)
+
+func TestCatchRecoversPanic(t *testing.T) {
+    calls := 0
+    err := Catch(func() error {
+        calls++
+        return nil
+    }, func() error {
+        panic("boom")
+    }, func() error {
+        calls++
+        return nil
+    })
+    if calls != 1 || !errors.Is(err, ErrExceptionPanic) || err.Error() != "panic: boom" {
+        t.Fatalf("err=%v calls=%d", err, calls)
+    }
+    if v, ok := PanicValue(err); !ok || v != "boom" {
+        t.Errorf("PanicValue = %v, %v", v, ok)
+    }
+    var ex Exception
+    if !errors.As(err, &ex) {
+        t.Fatalf("panic 应转换为 Exception")
+    }
+    if stack, _ := PanicStack(err); !strings.Contains(stack, "TestCatchRecoversPanic") {
+        t.Errorf("缺少调用栈")
+    }
+    if trace := fmt.Sprintf("%+v", err); !strings.Contains(trace, "TestCatchRecoversPanic") {
+        t.Errorf("%%+v 应包含调用栈: %q", trace)
+    }
+}
+
+func TestPanicExceptionJSON(t *testing.T) {
+    ex := NewPanicException("p", func() {})
+    data, err := ex.MarshalJSON()
+    if err != nil {
+        t.Fatalf("无法编码的 panic 值不应导致编码失败: %v", err)
+    }
+    if strings.Contains(string(data), "goroutine") || strings.Contains(string(data), `"stack"`) {
+        t.Errorf("JSON 不应包含调用栈: %s", data)
+    }
+    var buf strings.Builder
+    slog.New(slog.NewTextHandler(&buf, nil)).Error("failed", "err", ex)
+    if !strings.Contains(buf.String(), "err.stack=") {
+        t.Errorf("LogValue 应包含调用栈: %s", buf.String())
+    }
+}
+
+func TestTryFinally(t *testing.T) {
+    primary := errors.New("primary")
+    cleanup := errors.New("cleanup")
+
+    t.Run("finally总会执行", func(t *testing.T) {
+        var order []string
+        err := TryFinally(func() error {
+            order = append(order, "try")
+            panic(primary)
+        }, func() error {
+            order = append(order, "f1")
+            return nil
+        }, func() error {
+            order = append(order, "f2")
+            return nil
+        })
+        if strings.Join(order, ",") != "try,f1,f2" {
+            t.Errorf("执行顺序 = %v", order)
+        }
+        if !errors.Is(err, ErrExceptionPanic) || !errors.Is(err, primary) {
+            t.Errorf("err = %v", err)
+        }
+    })
+
+    t.Run("清理错误与主错误合并", func(t *testing.T) {
+        err := TryFinally(func() error {
+            return primary
+        }, func() error {
+            return cleanup
+        }, func() error {
+            panic("cleanup panic")
+        })
+        var errs *Errors
+        if !errors.As(err, &errs) || errs.Len() != 3 {
+            t.Fatalf("err = %v", err)
+        }
+        list := errs.Errors()
+        if list[0] != primary || list[1] != cleanup || !errors.Is(list[2], ErrExceptionPanic) {
+            t.Errorf("list = %v", list)
+        }
+    })
+
+    t.Run("单个错误原样返回", func(t *testing.T) {
+        if err := TryFinally(func() error { return nil }, func() error { return cleanup }); err != cleanup {
+            t.Errorf("err = %v", err)
+        }
+        if err := TryFinally(func() error { return nil }); err != nil {
+            t.Errorf("err = %v", err)
+        }
+    })
+
+    t.Run("catch处理错误", func(t *testing.T) {
+        finally := false
+        err := TryCatchFinally(func() error {
+            return primary
+        }, func(err error) error {
+            if err != primary {
+                t.Errorf("catch 收到 %v", err)
+            }
+            return nil
+        }, func() error {
+            finally = true
+            return nil
+        })
+        if err != nil || !finally {
+            t.Errorf("err=%v finally=%v", err, finally)
+        }
+    })
+}
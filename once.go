@@ -0,0 +1,110 @@
+package rr
+
+import (
+    "context"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+
+// 带返回值的 Once: f 成功后缓存结果, 失败时不缓存, 下次调用会再次执行 f, 直到成功
+// 零值可用且永不过期; 同一时刻只有一个 goroutine 执行 f, 其余调用方等待其结果
+type OnceValue[T any] struct {
+    result  atomic.Pointer[onceResult[T]]
+    ttl     time.Duration
+    clock   Clock
+    mu      sync.Mutex
+    running chan struct{}
+    // Reset 时递增, 用于丢弃 Reset 之前开始的执行结果
+    gen uint64
+}
+
+type onceResult[T any] struct {
+    val     T
+    expires time.Time
+}
+
+// 结果缓存 ttl 后过期, 过期后的下一次调用重新执行 f; clock 为 nil 时使用 SystemClock
+func NewOnceValueTTL[T any](ttl time.Duration, clock Clock) *OnceValue[T] {
+    if clock == nil {
+        clock = SystemClock
+    }
+    return &OnceValue[T]{ttl: ttl, clock: clock}
+}
+
+func (o *OnceValue[T]) Do(f func() (T, error)) (T, error) {
+    return o.DoCtx(context.Background(), f)
+}
+
+// 与 Do 相同, 但等待其它 goroutine 执行 f 期间 ctx 结束时返回 ctx.Err()
+// ctx 只控制等待, 不会中断正在执行的 f
+func (o *OnceValue[T]) DoCtx(ctx context.Context, f func() (T, error)) (T, error) {
+    for {
+        if v, ok := o.load(); ok {
+            return v, nil
+        }
+        o.mu.Lock()
+        if v, ok := o.load(); ok {
+            o.mu.Unlock()
+            return v, nil
+        }
+        if running := o.running; running != nil {
+            o.mu.Unlock()
+            select {
+            case <-running:
+                continue
+            case <-ctx.Done():
+                var zero T
+                return zero, ctx.Err()
+            }
+        }
+        if err := ctx.Err(); err != nil {
+            o.mu.Unlock()
+            var zero T
+            return zero, err
+        }
+        running := make(chan struct{})
+        o.running = running
+        gen := o.gen
+        o.mu.Unlock()
+        return o.doSlow(f, running, gen)
+    }
+}
+
+func (o *OnceValue[T]) doSlow(f func() (T, error), running chan struct{}, gen uint64) (v T, err error) {
+    done := false
+    defer func() {
+        o.mu.Lock()
+        if done && err == nil && gen == o.gen {
+            r := &onceResult[T]{val: v}
+            if o.ttl > 0 {
+                r.expires = o.clock.Now().Add(o.ttl)
+            }
+            o.result.Store(r)
+        }
+        o.running = nil
+        close(running)
+        o.mu.Unlock()
+    }()
+    v, err = f()
+    done = true
+    return v, err
+}
+
+// 已缓存且未过期的结果
+func (o *OnceValue[T]) load() (T, bool) {
+    r := o.result.Load()
+    if r == nil || (!r.expires.IsZero() && !o.clock.Now().Before(r.expires)) {
+        var zero T
+        return zero, false
+    }
+    return r.val, true
+}
+
+// 清除缓存的结果, 下一次调用重新执行 f; 正在执行的 f 的结果会被丢弃
+func (o *OnceValue[T]) Reset() {
+    o.mu.Lock()
+    defer o.mu.Unlock()
+    o.gen++
+    o.result.Store(nil)
+}
@@ -0,0 +1,138 @@
+package rr
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+func TestOnceValue(t *testing.T) {
+    var o OnceValue[int]
+    fail := errors.New("fail")
+    calls := 0
+    f := func() (int, error) {
+        calls++
+        if calls < 3 {
+            return 0, fail
+        }
+        return calls, nil
+    }
+    for i := 0; i < 2; i++ {
+        if _, err := o.Do(f); err != fail {
+            t.Fatalf("err = %v", err)
+        }
+    }
+    for i := 0; i < 2; i++ {
+        if v, err := o.Do(f); v != 3 || err != nil {
+            t.Fatalf("v=%v err=%v", v, err)
+        }
+    }
+    if calls != 3 {
+        t.Errorf("成功后不应再执行, calls = %d", calls)
+    }
+
+    o.Reset()
+    if v, _ := o.Do(f); v != 4 {
+        t.Errorf("Reset 后应重新执行, v = %d", v)
+    }
+}
+
+func TestOnceValueConcurrent(t *testing.T) {
+    var o OnceValue[int]
+    var calls atomic.Int32
+    var wg sync.WaitGroup
+    for i := 0; i < 10; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            v, err := o.Do(func() (int, error) {
+                calls.Add(1)
+                time.Sleep(5 * time.Millisecond)
+                return 7, nil
+            })
+            if v != 7 || err != nil {
+                t.Errorf("v=%v err=%v", v, err)
+            }
+        }()
+    }
+    wg.Wait()
+    if calls.Load() != 1 {
+        t.Errorf("calls = %d", calls.Load())
+    }
+}
+
+func TestOnceValueDoCtx(t *testing.T) {
+    var o OnceValue[string]
+    release := make(chan struct{})
+    started := make(chan struct{})
+    go o.Do(func() (string, error) {
+        close(started)
+        <-release
+        return "ready", nil
+    })
+    <-started
+
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    defer cancel()
+    if _, err := o.DoCtx(ctx, func() (string, error) { return "other", nil }); !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("等待方应可放弃, err = %v", err)
+    }
+    close(release)
+    if v, err := o.DoCtx(context.Background(), func() (string, error) { return "other", nil }); v != "ready" || err != nil {
+        t.Errorf("v=%v err=%v", v, err)
+    }
+
+    // Reset 之前开始的执行结果不会被缓存
+    var r OnceValue[int]
+    release = make(chan struct{})
+    started = make(chan struct{})
+    go r.Do(func() (int, error) {
+        close(started)
+        <-release
+        return 1, nil
+    })
+    <-started
+    r.Reset()
+    close(release)
+    if v, _ := r.Do(func() (int, error) { return 2, nil }); v != 2 {
+        t.Errorf("v = %d", v)
+    }
+}
+
+func TestOnceValueTTL(t *testing.T) {
+    c := NewManualClock(time.Now())
+    o := NewOnceValueTTL[int](time.Minute, c)
+    calls := 0
+    f := func() (int, error) {
+        calls++
+        return calls, nil
+    }
+    o.Do(f)
+    c.Advance(59 * time.Second)
+    if v, _ := o.Do(f); v != 1 {
+        t.Errorf("未过期 v = %d", v)
+    }
+    c.Advance(time.Second)
+    if v, _ := o.Do(f); v != 2 {
+        t.Errorf("过期后应重新执行, v = %d", v)
+    }
+}
+
+func TestOnceReset(t *testing.T) {
+    var o Once
+    n := 0
+    f := func() error {
+        n++
+        return nil
+    }
+    o.Do(f)
+    o.Do(f)
+    o.Reset()
+    o.Do(f)
+    if n != 2 {
+        t.Errorf("n = %d", n)
+    }
+}
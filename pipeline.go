@@ -0,0 +1,402 @@
+package rr
+
+import (
+    "context"
+    "sync"
+    "time"
+)
+
+// 流水线, 负责各阶段 goroutine 的生命周期与错误处理
+// 默认任一阶段出错时取消整条流水线; 收集模式下出错的元素被丢弃, 其余继续处理
+type Pipeline struct {
+    ctx     context.Context
+    cancel  context.CancelCauseFunc
+    collect bool
+    limit   CC
+    clock   Clock
+    wg      sync.WaitGroup
+    errOnce sync.Once
+    err     error
+    errs    Errors
+}
+
+type PipelineOption func(*Pipeline)
+
+// 收集所有错误, Wait 返回聚合后的 *Errors
+func PipelineCollectErrors() PipelineOption {
+    return func(r *Pipeline) {
+        r.collect = true
+    }
+}
+
+// 所有 Map 阶段共用的并发上限, 每处理一个元素占用一个名额
+func PipelineLimit(cc CC) PipelineOption {
+    return func(r *Pipeline) {
+        r.limit = cc
+    }
+}
+
+// Batch 使用的时钟, 默认 SystemClock
+func PipelineClock(c Clock) PipelineOption {
+    return func(r *Pipeline) {
+        if c != nil {
+            r.clock = c
+        }
+    }
+}
+
+func NewPipeline(ctx context.Context, opts ...PipelineOption) *Pipeline {
+    r := &Pipeline{clock: SystemClock}
+    for _, opt := range opts {
+        opt(r)
+    }
+    r.ctx, r.cancel = context.WithCancelCause(ctx)
+    return r
+}
+
+// 流水线的 ctx, 默认模式下首个错误出现时被取消
+func (p *Pipeline) Context() context.Context {
+    return p.ctx
+}
+
+// 等待所有阶段结束, 返回首个错误, 收集模式下返回 *Errors; 父 ctx 结束时返回其原因
+func (p *Pipeline) Wait() error {
+    p.wg.Wait()
+    var err error
+    if p.collect {
+        err = p.errs.ErrorOrNil()
+    } else {
+        err = p.err
+    }
+    if err == nil && p.ctx.Err() != nil {
+        err = context.Cause(p.ctx)
+    }
+    p.cancel(nil)
+    return err
+}
+
+func (p *Pipeline) fail(err error) {
+    if err == nil {
+        return
+    }
+    if p.collect {
+        p.errs.Append(err)
+        return
+    }
+    p.errOnce.Do(func() {
+        p.err = err
+        p.cancel(err)
+    })
+}
+
+// 启动一个阶段, 结束时关闭 out
+func pipelineStage[T any](p *Pipeline, out chan T, fn func()) <-chan T {
+    p.wg.Add(1)
+    go func() {
+        defer p.wg.Done()
+        defer close(out)
+        fn()
+    }()
+    return out
+}
+
+// 执行阶段函数, panic 转换为错误
+func pipelineCall[T any](p *Pipeline, fn func() (T, error)) (v T, err error) {
+    defer func() {
+        if r := recover(); r != nil {
+            err = NewPanicException("pipeline stage panicked", r)
+        }
+    }()
+    return fn()
+}
+
+// 执行 Map 的 fn, 只在执行期间占用 PipelineLimit 的名额
+func pipelineMapCall[T, U any](p *Pipeline, fn func(ctx context.Context, v T) (U, error), v T) (U, error) {
+    if p.limit != nil {
+        if err := p.limit.AddN(p.ctx, 1); err != nil {
+            var zero U
+            return zero, err
+        }
+        defer p.limit.DoneN(1)
+    }
+    return pipelineCall(p, func() (U, error) {
+        return fn(p.ctx, v)
+    })
+}
+
+func pipelineSend[T any](ctx context.Context, out chan<- T, v T) bool {
+    select {
+    case out <- v:
+        return true
+    case <-ctx.Done():
+        return false
+    }
+}
+
+// 数据源, fn 通过 emit 产出数据, emit 返回 false 表示流水线已取消, fn 应尽快返回
+func Source[T any](p *Pipeline, fn func(ctx context.Context, emit func(T) bool) error) <-chan T {
+    out := make(chan T)
+    return pipelineStage(p, out, func() {
+        _, err := pipelineCall(p, func() (struct{}, error) {
+            return struct{}{}, fn(p.ctx, func(v T) bool {
+                return pipelineSend(p.ctx, out, v)
+            })
+        })
+        p.fail(err)
+    })
+}
+
+// 以切片为数据源
+func SourceSlice[T any](p *Pipeline, items []T) <-chan T {
+    return Source(p, func(ctx context.Context, emit func(T) bool) error {
+        for _, v := range items {
+            if !emit(v) {
+                return nil
+            }
+        }
+        return nil
+    })
+}
+
+type mapOptions struct {
+    ordered bool
+}
+
+type MapOption func(*mapOptions)
+
+// 按输入顺序输出
+func MapOrdered() MapOption {
+    return func(r *mapOptions) {
+        r.ordered = true
+    }
+}
+
+// 以 workers 个 goroutine 并发执行 fn, 默认按完成顺序输出
+func Map[T, U any](p *Pipeline, in <-chan T, workers int, fn func(ctx context.Context, v T) (U, error), opts ...MapOption) <-chan U {
+    var o mapOptions
+    for _, opt := range opts {
+        opt(&o)
+    }
+    workers = max(workers, 1)
+    out := make(chan U)
+    if o.ordered {
+        return pipelineStage(p, out, func() {
+            mapOrdered(p, in, out, workers, fn)
+        })
+    }
+    return pipelineStage(p, out, func() {
+        var wg sync.WaitGroup
+        for i := 0; i < workers; i++ {
+            wg.Add(1)
+            go func() {
+                defer wg.Done()
+                for v := range pipelineRecv(p, in) {
+                    u, err := pipelineMapCall(p, fn, v)
+                    if err != nil {
+                        p.fail(err)
+                        continue
+                    }
+                    if !pipelineSend(p.ctx, out, u) {
+                        return
+                    }
+                }
+            }()
+        }
+        wg.Wait()
+    })
+}
+
+type mapResult[U any] struct {
+    v  U
+    ok bool
+}
+
+// 每个元素分配一个结果槽, 按分配顺序等待结果并输出
+func mapOrdered[T, U any](p *Pipeline, in <-chan T, out chan<- U, workers int, fn func(ctx context.Context, v T) (U, error)) {
+    type job struct {
+        v    T
+        slot chan mapResult[U]
+    }
+    jobs := make(chan job)
+    slots := make(chan chan mapResult[U], workers)
+    var wg sync.WaitGroup
+    for i := 0; i < workers; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for j := range jobs {
+                u, err := pipelineMapCall(p, fn, j.v)
+                p.fail(err)
+                j.slot <- mapResult[U]{v: u, ok: err == nil}
+            }
+        }()
+    }
+    go func() {
+        defer close(slots)
+        defer close(jobs)
+        for v := range pipelineRecv(p, in) {
+            // 先交给 worker 再登记槽, 登记过的槽一定会被填充
+            slot := make(chan mapResult[U], 1)
+            select {
+            case jobs <- job{v: v, slot: slot}:
+            case <-p.ctx.Done():
+                return
+            }
+            select {
+            case slots <- slot:
+            case <-p.ctx.Done():
+                return
+            }
+        }
+    }()
+    defer wg.Wait()
+    for slot := range slots {
+        r := <-slot
+        if r.ok && !pipelineSend(p.ctx, out, r.v) {
+            // 取消后继续取出剩余的槽, 保证分发与工作 goroutine 退出
+            for slot := range slots {
+                <-slot
+            }
+            return
+        }
+    }
+}
+
+// 只保留 fn 返回 true 的元素
+func Filter[T any](p *Pipeline, in <-chan T, fn func(v T) bool) <-chan T {
+    out := make(chan T)
+    return pipelineStage(p, out, func() {
+        for v := range pipelineRecv(p, in) {
+            keep, err := pipelineCall(p, func() (bool, error) {
+                return fn(v), nil
+            })
+            if err != nil {
+                p.fail(err)
+                continue
+            }
+            if keep && !pipelineSend(p.ctx, out, v) {
+                return
+            }
+        }
+    })
+}
+
+// 积累到 n 个, 或批次中首个元素到达 d 之后输出一批; 输入结束时输出剩余的元素
+func Batch[T any](p *Pipeline, in <-chan T, n int, d time.Duration) <-chan []T {
+    n = max(n, 1)
+    out := make(chan []T)
+    return pipelineStage(p, out, func() {
+        var batch []T
+        var timer ClockTimer
+        var timeout <-chan time.Time
+        flush := func() bool {
+            if timer != nil {
+                timer.Stop()
+                timer, timeout = nil, nil
+            }
+            if len(batch) == 0 {
+                return true
+            }
+            b := batch
+            batch = nil
+            return pipelineSend(p.ctx, out, b)
+        }
+        defer func() {
+            if timer != nil {
+                timer.Stop()
+            }
+        }()
+        for {
+            select {
+            case v, ok := <-in:
+                if !ok {
+                    flush()
+                    return
+                }
+                batch = append(batch, v)
+                if len(batch) >= n {
+                    if !flush() {
+                        return
+                    }
+                } else if timer == nil && d > 0 {
+                    timer = p.clock.NewTimer(d)
+                    timeout = timer.C()
+                }
+            case <-timeout:
+                timer, timeout = nil, nil
+                if !flush() {
+                    return
+                }
+            case <-p.ctx.Done():
+                return
+            }
+        }
+    })
+}
+
+// 把输入分发到 n 个输出, 每个元素只会被一个输出取走
+func FanOut[T any](p *Pipeline, in <-chan T, n int) []<-chan T {
+    n = max(n, 1)
+    outs := make([]<-chan T, n)
+    for i := range outs {
+        out := make(chan T)
+        outs[i] = pipelineStage(p, out, func() {
+            for v := range pipelineRecv(p, in) {
+                if !pipelineSend(p.ctx, out, v) {
+                    return
+                }
+            }
+        })
+    }
+    return outs
+}
+
+// 合并多个输入, 全部输入结束后关闭输出
+func FanIn[T any](p *Pipeline, ins ...<-chan T) <-chan T {
+    out := make(chan T)
+    return pipelineStage(p, out, func() {
+        var wg sync.WaitGroup
+        for _, in := range ins {
+            wg.Add(1)
+            go func(in <-chan T) {
+                defer wg.Done()
+                for v := range pipelineRecv(p, in) {
+                    if !pipelineSend(p.ctx, out, v) {
+                        return
+                    }
+                }
+            }(in)
+        }
+        wg.Wait()
+    })
+}
+
+// 消费输入, 在 Wait 返回前处理完所有元素
+func Sink[T any](p *Pipeline, in <-chan T, fn func(ctx context.Context, v T) error) {
+    p.wg.Add(1)
+    go func() {
+        defer p.wg.Done()
+        for v := range pipelineRecv(p, in) {
+            _, err := pipelineCall(p, func() (struct{}, error) {
+                return struct{}{}, fn(p.ctx, v)
+            })
+            p.fail(err)
+        }
+    }()
+}
+
+// 依次产出 in 中的元素, 流水线取消后停止
+func pipelineRecv[T any](p *Pipeline, in <-chan T) func(yield func(T) bool) {
+    return func(yield func(T) bool) {
+        for {
+            select {
+            case v, ok := <-in:
+                if !ok || !yield(v) {
+                    return
+                }
+            case <-p.ctx.Done():
+                return
+            }
+        }
+    }
+}
@@ -0,0 +1,207 @@
+package rr
+
+import (
+    "context"
+    "errors"
+    "reflect"
+    "sort"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+func TestPipeline(t *testing.T) {
+    p := NewPipeline(context.Background())
+    src := SourceSlice(p, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+    squared := Map(p, src, 4, func(ctx context.Context, v int) (int, error) {
+        // 让后面的元素先完成
+        time.Sleep(time.Duration(10-v) * time.Millisecond)
+        return v * v, nil
+    }, MapOrdered())
+    even := Filter(p, squared, func(v int) bool {
+        return v%2 == 0
+    })
+    var got []int
+    Sink(p, even, func(ctx context.Context, v int) error {
+        got = append(got, v)
+        return nil
+    })
+    if err := p.Wait(); err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(got, []int{4, 16, 36, 64, 100}) {
+        t.Errorf("got = %v", got)
+    }
+}
+
+func TestPipelineFirstError(t *testing.T) {
+    p := NewPipeline(context.Background())
+    fail := errors.New("fail")
+    src := Source(p, func(ctx context.Context, emit func(int) bool) error {
+        for i := 0; ; i++ {
+            if !emit(i) {
+                return nil
+            }
+        }
+    })
+    mapped := Map(p, src, 3, func(ctx context.Context, v int) (int, error) {
+        if v == 50 {
+            return 0, fail
+        }
+        return v, nil
+    })
+    Sink(p, mapped, func(ctx context.Context, v int) error {
+        return nil
+    })
+    // 无限的数据源在首个错误后被取消
+    if err := p.Wait(); err != fail {
+        t.Errorf("err = %v", err)
+    }
+    if !errors.Is(context.Cause(p.Context()), fail) {
+        t.Errorf("cause = %v", context.Cause(p.Context()))
+    }
+}
+
+func TestPipelineCollectErrors(t *testing.T) {
+    p := NewPipeline(context.Background(), PipelineCollectErrors())
+    src := SourceSlice(p, []int{1, 2, 3, 4, 5, 6})
+    mapped := Map(p, src, 2, func(ctx context.Context, v int) (int, error) {
+        if v%3 == 0 {
+            return 0, errors.New("bad")
+        }
+        if v == 4 {
+            panic("boom")
+        }
+        return v, nil
+    }, MapOrdered())
+    var got []int
+    Sink(p, mapped, func(ctx context.Context, v int) error {
+        got = append(got, v)
+        return nil
+    })
+    err := p.Wait()
+    var errs *Errors
+    if !errors.As(err, &errs) || errs.Len() != 3 || !errors.Is(err, ErrExceptionPanic) {
+        t.Errorf("err = %v", err)
+    }
+    if !reflect.DeepEqual(got, []int{1, 2, 5}) {
+        t.Errorf("got = %v", got)
+    }
+}
+
+func TestPipelineBatchFan(t *testing.T) {
+    c := NewManualClock(time.Now())
+    p := NewPipeline(context.Background(), PipelineClock(c))
+    flushed := make(chan struct{}, 8)
+    src := Source(p, func(ctx context.Context, emit func(int) bool) error {
+        for i := 1; i <= 5; i++ {
+            emit(i)
+        }
+        // 超过 d 后未满的批次也会输出
+        for c.PendingTimers() != 1 {
+            time.Sleep(time.Millisecond)
+        }
+        c.Advance(10 * time.Millisecond)
+        for i := 0; i < 3; i++ {
+            <-flushed
+        }
+        emit(6)
+        return nil
+    })
+    batches := Batch(p, src, 2, 10*time.Millisecond)
+    var got [][]int
+    Sink(p, batches, func(ctx context.Context, v []int) error {
+        got = append(got, v)
+        flushed <- struct{}{}
+        return nil
+    })
+    if err := p.Wait(); err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(got, [][]int{{1, 2}, {3, 4}, {5}, {6}}) {
+        t.Errorf("got = %v", got)
+    }
+
+    p = NewPipeline(context.Background())
+    outs := FanOut(p, SourceSlice(p, []int{1, 2, 3, 4, 5, 6, 7, 8}), 3)
+    var mu sync.Mutex
+    var all []int
+    Sink(p, FanIn(p, outs...), func(ctx context.Context, v int) error {
+        mu.Lock()
+        all = append(all, v)
+        mu.Unlock()
+        return nil
+    })
+    if err := p.Wait(); err != nil {
+        t.Fatal(err)
+    }
+    sort.Ints(all)
+    if !reflect.DeepEqual(all, []int{1, 2, 3, 4, 5, 6, 7, 8}) {
+        t.Errorf("all = %v", all)
+    }
+}
+
+func TestPipelineLimit(t *testing.T) {
+    cc := NewCC(2)
+    p := NewPipeline(context.Background(), PipelineLimit(cc))
+    var running, peak atomic.Int32
+    fn := func(ctx context.Context, v int) (int, error) {
+        n := running.Add(1)
+        for {
+            old := peak.Load()
+            if n <= old || peak.CompareAndSwap(old, n) {
+                break
+            }
+        }
+        time.Sleep(2 * time.Millisecond)
+        running.Add(-1)
+        return v, nil
+    }
+    // 两个 Map 阶段共用同一个上限
+    a := Map(p, SourceSlice(p, make([]int, 10)), 4, fn)
+    b := Map(p, a, 4, fn)
+    Sink(p, b, func(ctx context.Context, v int) error { return nil })
+    if err := p.Wait(); err != nil || peak.Load() > 2 {
+        t.Errorf("err=%v peak=%d", err, peak.Load())
+    }
+
+    // 上限为 1 时只有 Map 的 fn 占用名额, Source 与 Sink 不参与
+    for _, opts := range [][]MapOption{nil, {MapOrdered()}} {
+        p = NewPipeline(context.Background(), PipelineLimit(NewCC(1)))
+        var sum atomic.Int32
+        a := Map(p, SourceSlice(p, []int{1, 2, 3, 4}), 2, func(ctx context.Context, v int) (int, error) {
+            return v * 2, nil
+        }, opts...)
+        b := Map(p, a, 2, func(ctx context.Context, v int) (int, error) {
+            return v + 1, nil
+        }, opts...)
+        Sink(p, Filter(p, b, func(v int) bool { return v > 0 }), func(ctx context.Context, v int) error {
+            sum.Add(int32(v))
+            return nil
+        })
+        done := make(chan error, 1)
+        go func() {
+            done <- p.Wait()
+        }()
+        select {
+        case err := <-done:
+            if err != nil || sum.Load() != 24 {
+                t.Errorf("err=%v sum=%d", err, sum.Load())
+            }
+        case <-time.After(time.Second):
+            t.Fatal("上限为 1 时流水线死锁")
+        }
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    p = NewPipeline(ctx)
+    Sink(p, Source(p, func(ctx context.Context, emit func(int) bool) error {
+        cancel()
+        <-ctx.Done()
+        return nil
+    }), func(ctx context.Context, v int) error { return nil })
+    if err := p.Wait(); !errors.Is(err, context.Canceled) {
+        t.Errorf("父 ctx 取消时 err = %v", err)
+    }
+}
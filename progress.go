@@ -0,0 +1,178 @@
+package rr
+
+import (
+    "context"
+    "sync"
+    "time"
+)
+
+// 任务进度快照
+type Progress struct {
+    Done  int64
+    Total int64
+    Stage string
+    // 最后一次更新的时间, 从未上报时为零值
+    Time time.Time
+}
+
+// 完成百分比 0~100, Total 未知时返回 0
+func (p Progress) Percent() float64 {
+    if p.Total <= 0 {
+        return 0
+    }
+    return float64(p.Done) / float64(p.Total) * 100
+}
+
+// 任务的时间信息, 任务未开始执行时 Start 为零值, 未结束时 End 为零值且 Duration 为已运行的时长
+type TaskTiming struct {
+    Start    time.Time
+    End      time.Time
+    Duration time.Duration
+}
+
+// 任务内上报进度, 通过 ProgressFromContext 从任务的 ctx 中取得
+type ProgressReporter interface {
+    // 设置已完成数与总数
+    Report(done, total int64)
+    // 已完成数增加 n
+    Add(n int64)
+    // 设置当前阶段描述
+    Stage(stage string)
+}
+
+type progressKey struct{}
+
+// 取得任务 ctx 中的进度上报器, ctx 不属于任务时返回一个忽略所有上报的实现
+func ProgressFromContext(ctx context.Context) ProgressReporter {
+    if p, ok := ctx.Value(progressKey{}).(*taskProgress); ok {
+        return p
+    }
+    return noopProgress{}
+}
+
+type noopProgress struct{}
+
+func (noopProgress) Report(done, total int64) {}
+func (noopProgress) Add(n int64)              {}
+func (noopProgress) Stage(stage string)       {}
+
+// 任务的进度与时间信息
+type taskProgress struct {
+    mu       sync.Mutex
+    current  Progress
+    start    time.Time
+    end      time.Time
+    finished bool
+    subs     map[chan Progress]struct{}
+}
+
+func (p *taskProgress) Report(done, total int64) {
+    p.update(func(c *Progress) {
+        c.Done, c.Total = done, total
+    })
+}
+
+func (p *taskProgress) Add(n int64) {
+    p.update(func(c *Progress) {
+        c.Done += n
+    })
+}
+
+func (p *taskProgress) Stage(stage string) {
+    p.update(func(c *Progress) {
+        c.Stage = stage
+    })
+}
+
+func (p *taskProgress) update(f func(*Progress)) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    if p.finished {
+        return
+    }
+    f(&p.current)
+    p.current.Time = time.Now()
+    for ch := range p.subs {
+        progressSend(ch, p.current)
+    }
+}
+
+// 只保留最新的一条, 订阅方消费慢时丢弃旧的进度
+func progressSend(ch chan Progress, v Progress) {
+    select {
+    case ch <- v:
+        return
+    default:
+    }
+    select {
+    case <-ch:
+    default:
+    }
+    select {
+    case ch <- v:
+    default:
+    }
+}
+
+func (p *taskProgress) started() {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    p.start = time.Now()
+}
+
+// 任务结束时记录结束时间并关闭所有订阅
+func (p *taskProgress) finish() {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    p.end = time.Now()
+    p.finished = true
+    for ch := range p.subs {
+        close(ch)
+    }
+    p.subs = nil
+}
+
+func (p *taskProgress) snapshot() Progress {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    return p.current
+}
+
+func (p *taskProgress) subscribe() (<-chan Progress, func()) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    ch := make(chan Progress, 1)
+    if !p.current.Time.IsZero() {
+        ch <- p.current
+    }
+    if p.finished {
+        close(ch)
+        return ch, func() {}
+    }
+    if p.subs == nil {
+        p.subs = make(map[chan Progress]struct{})
+    }
+    p.subs[ch] = struct{}{}
+    return ch, func() {
+        p.mu.Lock()
+        defer p.mu.Unlock()
+        if _, ok := p.subs[ch]; ok {
+            delete(p.subs, ch)
+            close(ch)
+        }
+    }
+}
+
+func (p *taskProgress) timing() TaskTiming {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    t := TaskTiming{Start: p.start, End: p.end}
+    switch {
+    case p.start.IsZero():
+    case p.end.IsZero():
+        t.Duration = time.Since(p.start)
+    default:
+        t.Duration = p.end.Sub(p.start)
+    }
+    return t
+}
@@ -0,0 +1,94 @@
+package rr
+
+import (
+    "context"
+    "testing"
+)
+
+func TestTaskProgress(t *testing.T) {
+    step := make(chan struct{})
+    task := Async(context.Background(), func(ctx context.Context) error {
+        p := ProgressFromContext(ctx)
+        p.Stage("download")
+        p.Report(1, 4)
+        <-step
+        p.Add(2)
+        <-step
+        return nil
+    })
+    sub, _ := task.SubscribeProgress()
+
+    var last Progress
+    for last.Done != 1 {
+        last = <-sub
+    }
+    if last.Total != 4 || last.Stage != "download" || last.Percent() != 25 {
+        t.Errorf("progress = %+v", last)
+    }
+    step <- struct{}{}
+    for last.Done != 3 {
+        last = <-sub
+    }
+    if p := task.Progress(); p.Done != 3 || p.Percent() != 75 {
+        t.Errorf("progress = %+v", p)
+    }
+    if timing := task.Timing(); timing.Start.IsZero() || !timing.End.IsZero() || timing.Duration <= 0 {
+        t.Errorf("运行中 timing = %+v", timing)
+    }
+
+    step <- struct{}{}
+    task.Get()
+    for range sub {
+    }
+    timing := task.Timing()
+    if timing.End.Before(timing.Start) || timing.Duration != timing.End.Sub(timing.Start) {
+        t.Errorf("timing = %+v", timing)
+    }
+    // 结束后订阅立即收到最后的进度并关闭
+    after, _ := task.SubscribeProgress()
+    if p, ok := <-after; !ok || p.Done != 3 {
+        t.Errorf("p=%+v ok=%v", p, ok)
+    }
+    if _, ok := <-after; ok {
+        t.Error("通道应已关闭")
+    }
+}
+
+func TestProgressUnsubscribe(t *testing.T) {
+    release := make(chan struct{})
+    task := AsyncResult(context.Background(), func(ctx context.Context) (int, error) {
+        <-release
+        ProgressFromContext(ctx).Report(1, 1)
+        return 1, nil
+    })
+    sub, cancel := task.SubscribeProgress()
+    cancel()
+    cancel()
+    if _, ok := <-sub; ok {
+        t.Error("取消订阅后通道应关闭")
+    }
+    close(release)
+    task.Get()
+    if task.Progress().Done != 1 {
+        t.Errorf("progress = %+v", task.Progress())
+    }
+
+    // 不属于任务的 ctx 上报不会出错
+    ProgressFromContext(context.Background()).Report(1, 2)
+
+    // 未开始执行就被取消的任务没有开始时间
+    e := NewExecutor(ExecutorWorkers(1, 1))
+    block := make(chan struct{})
+    e.Submit(context.Background(), func(ctx context.Context) error {
+        <-block
+        return nil
+    })
+    queued, _ := e.Submit(context.Background(), func(ctx context.Context) error { return nil })
+    queued.Cancel()
+    close(block)
+    queued.Get()
+    if timing := queued.Timing(); !timing.Start.IsZero() || timing.End.IsZero() || timing.Duration != 0 {
+        t.Errorf("timing = %+v", timing)
+    }
+    e.Shutdown(context.Background())
+}
@@ -0,0 +1,352 @@
+package rr
+
+import (
+    "context"
+    "math"
+    "sort"
+    "sync"
+    "time"
+)
+
+// 速率限制器, 拒绝时返回带 RetryAfter 等待时间的 ErrExceptionTooManyRequests
+type RateLimiter interface {
+    // 立即取得一个许可, 失败时不消耗许可
+    Allow() error
+    // 预约一个许可, 返回需等待的时间; 永远无法满足时返回错误且不预约
+    Reserve() (Reservation, error)
+    // 等待直到取得许可; ctx 在许可可用之前就会到期时立即返回错误
+    Wait(ctx context.Context) error
+}
+
+// 一次预约, 放弃使用时调用 Cancel 归还许可
+type Reservation struct {
+    delay  time.Duration
+    cancel func()
+    once   *sync.Once
+}
+
+// 距许可可用的等待时间
+func (r Reservation) Delay() time.Duration {
+    return r.delay
+}
+
+// 归还许可, 多次调用只生效一次
+func (r Reservation) Cancel() {
+    if r.once != nil {
+        r.once.Do(r.cancel)
+    }
+}
+
+type rateLimiterOptions struct {
+    clock Clock
+    idle  time.Duration
+}
+
+type RateLimiterOption func(*rateLimiterOptions)
+
+// 使用的时钟, 默认 SystemClock
+func RateLimiterClock(c Clock) RateLimiterOption {
+    return func(r *rateLimiterOptions) {
+        if c != nil {
+            r.clock = c
+        }
+    }
+}
+
+// 仅用于 KeyedRateLimiter: 超过 d 未使用的 key 会被清理, 默认 10 分钟
+func RateLimiterIdleTimeout(d time.Duration) RateLimiterOption {
+    return func(r *rateLimiterOptions) {
+        if d > 0 {
+            r.idle = d
+        }
+    }
+}
+
+func newRateLimiterOptions(opts []RateLimiterOption) rateLimiterOptions {
+    o := rateLimiterOptions{clock: SystemClock, idle: 10 * time.Minute}
+    for _, opt := range opts {
+        opt(&o)
+    }
+    return o
+}
+
+func rateLimited(d time.Duration) error {
+    return RetryAfter(ErrExceptionTooManyRequests, d)
+}
+
+// 预约后等待, 供各实现复用
+func rateLimiterWait(ctx context.Context, clock Clock, l RateLimiter) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+    res, err := l.Reserve()
+    if err != nil {
+        return err
+    }
+    if res.delay <= 0 {
+        return nil
+    }
+    // deadline 是真实时间, 与注入的时钟无关, 按剩余时长比较
+    if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < res.delay {
+        res.Cancel()
+        return rateLimited(res.delay)
+    }
+    t := clock.NewTimer(res.delay)
+    defer t.Stop()
+    select {
+    case <-t.C():
+        return nil
+    case <-ctx.Done():
+        res.Cancel()
+        return ctx.Err()
+    }
+}
+
+type tokenBucket struct {
+    mu     sync.Mutex
+    clock  Clock
+    rate   float64
+    burst  float64
+    tokens float64
+    last   time.Time
+}
+
+// 令牌桶, 每秒补充 rate 个令牌, 最多积累 burst 个, 初始为满
+// rate <= 0 时不再补充, 用完 burst 后一直拒绝
+func NewTokenBucket(rate float64, burst int, opts ...RateLimiterOption) RateLimiter {
+    o := newRateLimiterOptions(opts)
+    r := &tokenBucket{
+        clock: o.clock,
+        rate:  math.Max(rate, 0),
+        burst: float64(max(burst, 0)),
+        last:  o.clock.Now(),
+    }
+    r.tokens = r.burst
+    return r
+}
+
+// 按流逝的时间补充令牌, 调用方需持有锁
+func (r *tokenBucket) advance() time.Time {
+    now := r.clock.Now()
+    if elapsed := now.Sub(r.last); elapsed > 0 {
+        r.tokens = math.Min(r.burst, r.tokens+elapsed.Seconds()*r.rate)
+        r.last = now
+    }
+    return now
+}
+
+// 令牌数从 tokens 涨到 1 所需的时间, 调用方需持有锁
+func (r *tokenBucket) delay(tokens float64) time.Duration {
+    if tokens >= 1 {
+        return 0
+    }
+    return time.Duration(math.Ceil((1 - tokens) / r.rate * float64(time.Second)))
+}
+
+func (r *tokenBucket) Allow() error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.advance()
+    if r.tokens >= 1 {
+        r.tokens--
+        return nil
+    }
+    // 不会再有令牌时不给出等待时长, 避免调用方按 RetryAfter 无限重试
+    if r.rate == 0 || r.burst < 1 {
+        return ErrExceptionTooManyRequests
+    }
+    return rateLimited(r.delay(r.tokens))
+}
+
+func (r *tokenBucket) Reserve() (Reservation, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.advance()
+    if r.tokens < 1 && (r.rate == 0 || r.burst < 1) {
+        return Reservation{}, ErrExceptionTooManyRequests
+    }
+    d := r.delay(r.tokens)
+    r.tokens--
+    return Reservation{delay: d, once: new(sync.Once), cancel: func() {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        r.advance()
+        r.tokens = math.Min(r.burst, r.tokens+1)
+    }}, nil
+}
+
+func (r *tokenBucket) Wait(ctx context.Context) error {
+    return rateLimiterWait(ctx, r.clock, r)
+}
+
+type slidingWindow struct {
+    mu     sync.Mutex
+    clock  Clock
+    limit  int
+    window time.Duration
+    // 按时间排序的许可记录, 预约的许可记录在未来的时间点
+    log []time.Time
+}
+
+// 滑动窗口日志, 任意长度为 window 的时间段内最多放行 limit 个请求
+func NewSlidingWindow(limit int, window time.Duration, opts ...RateLimiterOption) RateLimiter {
+    o := newRateLimiterOptions(opts)
+    return &slidingWindow{
+        clock:  o.clock,
+        limit:  max(limit, 0),
+        window: window,
+    }
+}
+
+// 清理窗口外的记录并返回下一个许可可用的时间, 调用方需持有锁
+func (r *slidingWindow) next() (now, at time.Time) {
+    now = r.clock.Now()
+    i := sort.Search(len(r.log), func(i int) bool {
+        return r.log[i].After(now.Add(-r.window))
+    })
+    r.log = r.log[i:]
+    at = now
+    // 已有预约时排在其后, 保证先来先得
+    if n := len(r.log); n > 0 && r.log[n-1].After(at) {
+        at = r.log[n-1]
+    }
+    if n := len(r.log); n >= r.limit {
+        if t := r.log[n-r.limit].Add(r.window); t.After(at) {
+            at = t
+        }
+    }
+    return now, at
+}
+
+func (r *slidingWindow) Allow() error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if r.limit == 0 {
+        return ErrExceptionTooManyRequests
+    }
+    now, at := r.next()
+    if at.After(now) {
+        return rateLimited(at.Sub(now))
+    }
+    r.log = append(r.log, now)
+    return nil
+}
+
+func (r *slidingWindow) Reserve() (Reservation, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if r.limit == 0 {
+        return Reservation{}, ErrExceptionTooManyRequests
+    }
+    now, at := r.next()
+    r.log = append(r.log, at)
+    return Reservation{delay: at.Sub(now), once: new(sync.Once), cancel: func() {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        for i := len(r.log) - 1; i >= 0; i-- {
+            if r.log[i].Equal(at) {
+                r.log = append(r.log[:i], r.log[i+1:]...)
+                return
+            }
+        }
+    }}, nil
+}
+
+func (r *slidingWindow) Wait(ctx context.Context) error {
+    return rateLimiterWait(ctx, r.clock, r)
+}
+
+// 按 key 分别限速, 所有 key 共用同一个构造函数创建的配置
+type KeyedRateLimiter[K comparable] interface {
+    Allow(key K) error
+    Reserve(key K) (Reservation, error)
+    Wait(ctx context.Context, key K) error
+    // 当前保留的 key 数量
+    Len() int
+}
+
+type keyedLimiter struct {
+    limiter RateLimiter
+    // 最后使用时间, 有预约时为预约的许可可用时间
+    used time.Time
+}
+
+type keyedRateLimiter[K comparable] struct {
+    mu         sync.Mutex
+    newLimiter func() RateLimiter
+    clock      Clock
+    idle       time.Duration
+    limiters   map[K]*keyedLimiter
+    lastSweep  time.Time
+}
+
+// newLimiter 为每个新 key 创建限制器, 空闲的 key 在后续访问时被惰性清理
+// newLimiter 创建的限制器应与 RateLimiterClock 使用同一个时钟
+func NewKeyedRateLimiter[K comparable](newLimiter func() RateLimiter, opts ...RateLimiterOption) KeyedRateLimiter[K] {
+    o := newRateLimiterOptions(opts)
+    return &keyedRateLimiter[K]{
+        newLimiter: newLimiter,
+        clock:      o.clock,
+        idle:       o.idle,
+        limiters:   make(map[K]*keyedLimiter),
+        lastSweep:  o.clock.Now(),
+    }
+}
+
+// 取得 key 对应的限制器并清理空闲的 key
+func (r *keyedRateLimiter[K]) get(key K) *keyedLimiter {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    now := r.clock.Now()
+    if now.Sub(r.lastSweep) >= r.idle {
+        for k, l := range r.limiters {
+            if now.Sub(l.used) >= r.idle {
+                delete(r.limiters, k)
+            }
+        }
+        r.lastSweep = now
+    }
+    l, ok := r.limiters[key]
+    if !ok {
+        l = &keyedLimiter{limiter: r.newLimiter()}
+        r.limiters[key] = l
+    }
+    if now.After(l.used) {
+        l.used = now
+    }
+    return l
+}
+
+func (r *keyedRateLimiter[K]) touch(l *keyedLimiter, d time.Duration) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if t := r.clock.Now().Add(d); t.After(l.used) {
+        l.used = t
+    }
+}
+
+func (r *keyedRateLimiter[K]) Allow(key K) error {
+    return r.get(key).limiter.Allow()
+}
+
+func (r *keyedRateLimiter[K]) Reserve(key K) (Reservation, error) {
+    l := r.get(key)
+    res, err := l.limiter.Reserve()
+    if err == nil {
+        r.touch(l, res.delay)
+    }
+    return res, err
+}
+
+func (r *keyedRateLimiter[K]) Wait(ctx context.Context, key K) error {
+    l := r.get(key)
+    err := l.limiter.Wait(ctx)
+    r.touch(l, 0)
+    return err
+}
+
+func (r *keyedRateLimiter[K]) Len() int {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return len(r.limiters)
+}
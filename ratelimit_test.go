@@ -0,0 +1,149 @@
+package rr
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+)
+
+func TestTokenBucket(t *testing.T) {
+    c := NewManualClock(time.Now())
+    l := NewTokenBucket(2, 3, RateLimiterClock(c))
+    for i := 0; i < 3; i++ {
+        if err := l.Allow(); err != nil {
+            t.Fatalf("第 %d 次 err = %v", i, err)
+        }
+    }
+    err := l.Allow()
+    if !errors.Is(err, ErrExceptionTooManyRequests) {
+        t.Fatalf("err = %v", err)
+    }
+    if d, ok := RetryAfterDelay(err); !ok || d != 500*time.Millisecond {
+        t.Errorf("retry after = %v", d)
+    }
+
+    c.Advance(500 * time.Millisecond)
+    if err := l.Allow(); err != nil {
+        t.Errorf("补充后 err = %v", err)
+    }
+
+    r1, _ := l.Reserve()
+    r2, _ := l.Reserve()
+    if r1.Delay() != 500*time.Millisecond || r2.Delay() != time.Second {
+        t.Errorf("delay = %v %v", r1.Delay(), r2.Delay())
+    }
+    r2.Cancel()
+    r2.Cancel()
+    c.Advance(time.Second)
+    // 归还一个后 1 秒补充 2 个, 扣除 r1 剩 1 个
+    if err := l.Allow(); err != nil {
+        t.Errorf("err = %v", err)
+    }
+    if err := l.Allow(); err == nil {
+        t.Error("应被拒绝")
+    }
+
+    if _, err := NewTokenBucket(0, 0).Reserve(); !errors.Is(err, ErrExceptionTooManyRequests) {
+        t.Errorf("永远无法满足时 err = %v", err)
+    }
+    err = NewTokenBucket(10, 0).Allow()
+    if _, ok := RetryAfterDelay(err); !errors.Is(err, ErrExceptionTooManyRequests) || ok {
+        t.Errorf("burst 为 0 时不应给出等待时长, err = %v", err)
+    }
+}
+
+func TestSlidingWindow(t *testing.T) {
+    c := NewManualClock(time.Now())
+    l := NewSlidingWindow(2, time.Second, RateLimiterClock(c))
+    l.Allow()
+    c.Advance(400 * time.Millisecond)
+    l.Allow()
+    err := l.Allow()
+    if d, ok := RetryAfterDelay(err); !errors.Is(err, ErrExceptionTooManyRequests) || !ok || d != 600*time.Millisecond {
+        t.Fatalf("err=%v d=%v", err, d)
+    }
+    c.Advance(600 * time.Millisecond)
+    if err := l.Allow(); err != nil {
+        t.Errorf("窗口滑出后 err = %v", err)
+    }
+
+    r, _ := l.Reserve()
+    if r.Delay() != 400*time.Millisecond {
+        t.Errorf("delay = %v", r.Delay())
+    }
+    // 已有预约时后来者排在其后
+    if err := l.Allow(); err == nil {
+        t.Error("应被拒绝")
+    }
+    r.Cancel()
+    c.Advance(400 * time.Millisecond)
+    if err := l.Allow(); err != nil {
+        t.Errorf("err = %v", err)
+    }
+}
+
+func TestRateLimiterWait(t *testing.T) {
+    c := NewManualClock(time.Now())
+    l := NewTokenBucket(1, 1, RateLimiterClock(c))
+    if err := l.Wait(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    done := make(chan error, 1)
+    go func() {
+        done <- l.Wait(context.Background())
+    }()
+    for c.PendingTimers() != 1 {
+        time.Sleep(time.Millisecond)
+    }
+    c.Advance(time.Second)
+    if err := <-done; err != nil {
+        t.Errorf("err = %v", err)
+    }
+
+    // ctx 在许可可用之前到期时立即返回, 需要等待 1 小时而 ctx 只剩半小时
+    slow := NewTokenBucket(1.0/3600, 1, RateLimiterClock(c))
+    slow.Allow()
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+    defer cancel()
+    if err := slow.Wait(ctx); !errors.Is(err, ErrExceptionTooManyRequests) {
+        t.Errorf("err = %v", err)
+    }
+
+    ctx, cancel = context.WithCancel(context.Background())
+    go func() {
+        done <- l.Wait(ctx)
+    }()
+    for c.PendingTimers() != 1 {
+        time.Sleep(time.Millisecond)
+    }
+    cancel()
+    if err := <-done; !errors.Is(err, context.Canceled) {
+        t.Errorf("err = %v", err)
+    }
+    // 取消的等待归还了许可
+    c.Advance(time.Second)
+    if err := l.Allow(); err != nil {
+        t.Errorf("err = %v", err)
+    }
+}
+
+func TestKeyedRateLimiter(t *testing.T) {
+    c := NewManualClock(time.Now())
+    k := NewKeyedRateLimiter[string](func() RateLimiter {
+        return NewTokenBucket(1, 1, RateLimiterClock(c))
+    }, RateLimiterClock(c), RateLimiterIdleTimeout(time.Minute))
+    if k.Allow("a") != nil || k.Allow("b") != nil {
+        t.Fatal("不同 key 互不影响")
+    }
+    if err := k.Allow("a"); !errors.Is(err, ErrExceptionTooManyRequests) {
+        t.Errorf("err = %v", err)
+    }
+    c.Advance(30 * time.Second)
+    k.Allow("a")
+    c.Advance(40 * time.Second)
+    k.Allow("c")
+    if k.Len() != 2 {
+        t.Errorf("空闲的 b 应被清理, len = %d", k.Len())
+    }
+}
@@ -0,0 +1,336 @@
+package rr
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "math"
+    "math/rand/v2"
+    "time"
+)
+
+const (
+    RetryDefaultAttempts = 3
+    RetryDefaultBase     = 100 * time.Millisecond
+    RetryDefaultMax      = 10 * time.Second
+)
+
+// 退避策略, attempt 为已失败的次数(从1开始), prev 为上一次等待的时长
+type Backoff func(attempt int, prev time.Duration) time.Duration
+
+// 固定间隔
+func BackoffConstant(d time.Duration) Backoff {
+    return func(int, time.Duration) time.Duration {
+        return d
+    }
+}
+
+// 指数退避: base * factor^(attempt-1), 不超过 max, factor <= 1 时按 2 处理
+func BackoffExponential(base, max time.Duration, factor float64) Backoff {
+    if factor <= 1 {
+        factor = 2
+    }
+    return func(attempt int, _ time.Duration) time.Duration {
+        d := float64(base) * math.Pow(factor, float64(attempt-1))
+        return capDuration(d, max)
+    }
+}
+
+// 去相关抖动退避: random(base, prev*3), 不超过 max
+func BackoffDecorrelatedJitter(base, max time.Duration) Backoff {
+    return func(_ int, prev time.Duration) time.Duration {
+        if prev < base {
+            prev = base
+        }
+        upper := float64(prev) * 3
+        if max > 0 && upper > float64(max) {
+            upper = float64(max)
+        }
+        if upper <= float64(base) {
+            return base
+        }
+        return capDuration(float64(base)+rand.Float64()*(upper-float64(base)), max)
+    }
+}
+
+// 斐波那契退避: base, base, 2*base, 3*base, 5*base ..., 不超过 max
+func BackoffFibonacci(base, max time.Duration) Backoff {
+    return func(attempt int, _ time.Duration) time.Duration {
+        a, b := 0.0, 1.0
+        for i := 1; i < attempt; i++ {
+            a, b = b, a+b
+            if max > 0 && b*float64(base) >= float64(max) {
+                return max
+            }
+        }
+        return capDuration(b*float64(base), max)
+    }
+}
+
+func capDuration(d float64, max time.Duration) time.Duration {
+    if max > 0 && d >= float64(max) {
+        return max
+    }
+    if d >= math.MaxInt64 {
+        return time.Duration(math.MaxInt64)
+    }
+    return time.Duration(d)
+}
+
+var (
+    // 默认可重试的异常
+    RetryableExceptions = []Exception{ErrExceptionNetwork, ErrExceptionTimeout, ErrExceptionTooManyRequests}
+    // 参与分类的内置异常, 不在可重试列表中的视为永久错误
+    builtinExceptions = []Exception{
+        ErrExceptionNetwork, ErrExceptionNotFound, ErrExceptionServer, ErrExceptionTimeout,
+        ErrExceptionUnauthorized, ErrExceptionForbidden, ErrExceptionTooManyRequests, ErrExceptionInvalidArgs,
+        ErrExceptionCircuitOpen, ErrExceptionPanic,
+    }
+)
+
+// 重试策略, 返回 true 表示该错误可以重试
+type RetryPolicy func(err error) bool
+
+// 任何错误都重试
+func RetryPolicyAlways(error) bool {
+    return true
+}
+
+// 基于内置异常分类: 命中 retryable 的可重试, 命中其它内置异常的视为永久错误, 其余错误可重试
+// retryable 为空时使用 RetryableExceptions
+func RetryPolicyException(retryable ...Exception) RetryPolicy {
+    if len(retryable) == 0 {
+        retryable = RetryableExceptions
+    }
+    return func(err error) bool {
+        for _, e := range retryable {
+            if errors.Is(err, e) {
+                return true
+            }
+        }
+        for _, e := range builtinExceptions {
+            if errors.Is(err, e) {
+                return false
+            }
+        }
+        return true
+    }
+}
+
+type permanentError struct {
+    err error
+}
+
+func (r *permanentError) Error() string {
+    return r.err.Error()
+}
+func (r *permanentError) Unwrap() error {
+    return r.err
+}
+
+// 标记为永久错误, 重试会立即停止并返回原始错误
+func Permanent(err error) error {
+    if err == nil {
+        return nil
+    }
+    return &permanentError{err: err}
+}
+
+// 是否被 Permanent 标记
+func IsPermanent(err error) bool {
+    var p *permanentError
+    return errors.As(err, &p)
+}
+
+type retryAfterError struct {
+    err   error
+    delay time.Duration
+}
+
+func (r *retryAfterError) Error() string {
+    return r.err.Error()
+}
+func (r *retryAfterError) Unwrap() error {
+    return r.err
+}
+
+// 指定下一次重试前的等待时长, 覆盖退避策略, 例如 HTTP 429 的 Retry-After
+func RetryAfter(err error, d time.Duration) error {
+    if err == nil {
+        return nil
+    }
+    return &retryAfterError{err: err, delay: d}
+}
+
+// 读取 RetryAfter 指定的等待时长
+func RetryAfterDelay(err error) (time.Duration, bool) {
+    var r *retryAfterError
+    if errors.As(err, &r) {
+        return r.delay, true
+    }
+    return 0, false
+}
+
+// 去掉 Permanent/RetryAfter 包装, 返回原始错误
+func unwrapRetryMark(err error) error {
+    for {
+        switch e := err.(type) {
+        case *permanentError:
+            err = e.err
+        case *retryAfterError:
+            err = e.err
+        default:
+            return err
+        }
+    }
+}
+
+type retryOptions struct {
+    attempts       int
+    backoff        Backoff
+    policy         RetryPolicy
+    maxElapsed     time.Duration
+    attemptTimeout time.Duration
+    onRetry        func(attempt int, err error, next time.Duration)
+}
+
+type RetryOption func(*retryOptions)
+
+// 最大执行次数(含第一次), <= 0 表示不限次数, 此时需配合 ctx 或 RetryMaxElapsed 使用
+func RetryAttempts(n int) RetryOption {
+    return func(o *retryOptions) {
+        o.attempts = n
+    }
+}
+
+// 设置退避策略
+func RetryBackoff(b Backoff) RetryOption {
+    return func(o *retryOptions) {
+        if b != nil {
+            o.backoff = b
+        }
+    }
+}
+
+// 设置重试策略, 默认 RetryPolicyException()
+func RetryIf(p RetryPolicy) RetryOption {
+    return func(o *retryOptions) {
+        if p != nil {
+            o.policy = p
+        }
+    }
+}
+
+// 重试总耗时上限, 下一次等待会超出上限时不再重试
+func RetryMaxElapsed(d time.Duration) RetryOption {
+    return func(o *retryOptions) {
+        o.maxElapsed = d
+    }
+}
+
+// 单次执行超时, 通过传给 fn 的 ctx 生效
+func RetryAttemptTimeout(d time.Duration) RetryOption {
+    return func(o *retryOptions) {
+        o.attemptTimeout = d
+    }
+}
+
+// 每次准备重试前回调, attempt 为刚失败的次数, next 为即将等待的时长
+func RetryOnRetry(f func(attempt int, err error, next time.Duration)) RetryOption {
+    return func(o *retryOptions) {
+        o.onRetry = f
+    }
+}
+
+// 重试被 ctx 中止时返回, 同时记录中止原因与最后一次执行的错误
+type RetryAbortedError struct {
+    Cause    error
+    LastErr  error
+    Attempts int
+}
+
+func (r *RetryAbortedError) Error() string {
+    if r.LastErr == nil {
+        return fmt.Sprintf("retry aborted after %d attempts: %v", r.Attempts, r.Cause)
+    }
+    return fmt.Sprintf("retry aborted after %d attempts: %v; last error: %v", r.Attempts, r.Cause, r.LastErr)
+}
+
+func (r *RetryAbortedError) Unwrap() []error {
+    if r.LastErr == nil {
+        return []error{r.Cause}
+    }
+    return []error{r.Cause, r.LastErr}
+}
+
+// 按选项重试 fn, 直到成功、次数/耗时用尽或 ctx 结束
+// 最后一次失败后不再等待; ctx 结束时立即返回 *RetryAbortedError
+// 被 Permanent 标记或策略判定为不可重试的错误会立即返回, RetryAfter 指定的等待时长优先于退避策略
+func RetryWithOptions(ctx context.Context, fn func(ctx context.Context) error, opts ...RetryOption) error {
+    o := retryOptions{
+        attempts: RetryDefaultAttempts,
+        backoff:  BackoffExponential(RetryDefaultBase, RetryDefaultMax, 2),
+        policy:   RetryPolicyException(),
+    }
+    for _, opt := range opts {
+        opt(&o)
+    }
+    start := time.Now()
+    var (
+        lastErr error
+        prev    time.Duration
+    )
+    for attempt := 1; ; attempt++ {
+        if ctx.Err() != nil {
+            return &RetryAbortedError{Cause: context.Cause(ctx), LastErr: lastErr, Attempts: attempt - 1}
+        }
+        err := retryCall(ctx, o.attemptTimeout, fn)
+        if err == nil {
+            return nil
+        }
+        lastErr = unwrapRetryMark(err)
+        // 执行期间 ctx 结束, 无论错误能否重试都视为中止
+        if ctx.Err() != nil {
+            return &RetryAbortedError{Cause: context.Cause(ctx), LastErr: lastErr, Attempts: attempt}
+        }
+        if IsPermanent(err) {
+            return lastErr
+        }
+        delay, ok := RetryAfterDelay(err)
+        if !ok && !o.policy(lastErr) {
+            return lastErr
+        }
+        if o.attempts > 0 && attempt >= o.attempts {
+            return lastErr
+        }
+        if !ok {
+            delay = o.backoff(attempt, prev)
+        }
+        prev = delay
+        if o.maxElapsed > 0 && time.Since(start)+delay > o.maxElapsed {
+            return lastErr
+        }
+        if o.onRetry != nil {
+            o.onRetry(attempt, lastErr, delay)
+        }
+        if delay <= 0 {
+            continue
+        }
+        timer := time.NewTimer(delay)
+        select {
+        case <-ctx.Done():
+            timer.Stop()
+            return &RetryAbortedError{Cause: context.Cause(ctx), LastErr: lastErr, Attempts: attempt}
+        case <-timer.C:
+        }
+    }
+}
+
+func retryCall(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+    if timeout <= 0 {
+        return fn(ctx)
+    }
+    c, cancel := context.WithTimeout(ctx, timeout)
+    defer cancel()
+    return fn(c)
+}
@@ -0,0 +1,233 @@
+package rr
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+)
+
+func TestBackoff(t *testing.T) {
+    tests := []struct {
+        name    string
+        backoff Backoff
+        want    []time.Duration
+    }{
+        {"固定间隔", BackoffConstant(time.Second), []time.Duration{time.Second, time.Second, time.Second}},
+        {"指数", BackoffExponential(time.Second, 5*time.Second, 2), []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}},
+        {"斐波那契", BackoffFibonacci(time.Second, 6*time.Second), []time.Duration{time.Second, time.Second, 2 * time.Second, 3 * time.Second, 5 * time.Second, 6 * time.Second}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var prev time.Duration
+            for i, want := range tt.want {
+                got := tt.backoff(i+1, prev)
+                if got != want {
+                    t.Errorf("第%d次 = %v, 期望 %v", i+1, got, want)
+                }
+                prev = got
+            }
+        })
+    }
+}
+
+func TestBackoffDecorrelatedJitter(t *testing.T) {
+    b := BackoffDecorrelatedJitter(10*time.Millisecond, time.Second)
+    var prev time.Duration
+    for i := 1; i <= 50; i++ {
+        d := b(i, prev)
+        if d < 10*time.Millisecond || d > time.Second {
+            t.Fatalf("第%d次 = %v, 超出范围", i, d)
+        }
+        prev = d
+    }
+}
+
+func TestRetryWithOptions(t *testing.T) {
+    t.Run("成功前重试", func(t *testing.T) {
+        calls := 0
+        var hooks []int
+        err := RetryWithOptions(context.Background(), func(ctx context.Context) error {
+            calls++
+            if calls < 3 {
+                return errors.New("fail")
+            }
+            return nil
+        }, RetryAttempts(5), RetryBackoff(BackoffConstant(time.Millisecond)), RetryOnRetry(func(attempt int, err error, next time.Duration) {
+            hooks = append(hooks, attempt)
+        }))
+        if err != nil || calls != 3 || len(hooks) != 2 {
+            t.Errorf("err=%v calls=%d hooks=%v", err, calls, hooks)
+        }
+    })
+
+    t.Run("最后一次失败后不再等待", func(t *testing.T) {
+        start := time.Now()
+        err := RetryWithOptions(context.Background(), func(ctx context.Context) error {
+            return errors.New("fail")
+        }, RetryAttempts(2), RetryBackoff(BackoffConstant(50*time.Millisecond)))
+        if err == nil || err.Error() != "fail" {
+            t.Errorf("err = %v", err)
+        }
+        if elapsed := time.Since(start); elapsed >= 100*time.Millisecond {
+            t.Errorf("耗时 %v, 最后一次失败后仍在等待", elapsed)
+        }
+    })
+
+    t.Run("取消时记录最后错误", func(t *testing.T) {
+        ctx, cancel := context.WithCancel(context.Background())
+        last := errors.New("last")
+        err := RetryWithOptions(ctx, func(ctx context.Context) error {
+            return last
+        }, RetryAttempts(0), RetryBackoff(BackoffConstant(time.Hour)), RetryOnRetry(func(int, error, time.Duration) {
+            cancel()
+        }))
+        var aborted *RetryAbortedError
+        if !errors.As(err, &aborted) || aborted.Attempts != 1 {
+            t.Fatalf("err = %v", err)
+        }
+        if !errors.Is(err, context.Canceled) || !errors.Is(err, last) {
+            t.Errorf("err = %v, 应同时包含取消原因与最后错误", err)
+        }
+    })
+
+    t.Run("最后一次执行期间取消", func(t *testing.T) {
+        ctx, cancel := context.WithCancel(context.Background())
+        last := errors.New("last")
+        err := RetryWithOptions(ctx, func(ctx context.Context) error {
+            cancel()
+            return last
+        }, RetryAttempts(1))
+        var aborted *RetryAbortedError
+        if !errors.As(err, &aborted) || aborted.Attempts != 1 {
+            t.Fatalf("err = %v", err)
+        }
+        if !errors.Is(err, context.Canceled) || !errors.Is(err, last) {
+            t.Errorf("err = %v, 应同时包含取消原因与最后错误", err)
+        }
+    })
+
+    t.Run("不可重试的错误执行期间取消", func(t *testing.T) {
+        ctx, cancel := context.WithCancel(context.Background())
+        err := RetryWithOptions(ctx, func(ctx context.Context) error {
+            cancel()
+            return Permanent(errors.New("permanent"))
+        })
+        var aborted *RetryAbortedError
+        if !errors.As(err, &aborted) {
+            t.Errorf("err = %v", err)
+        }
+    })
+
+    t.Run("总耗时上限", func(t *testing.T) {
+        calls := 0
+        err := RetryWithOptions(context.Background(), func(ctx context.Context) error {
+            calls++
+            return errors.New("fail")
+        }, RetryAttempts(0), RetryBackoff(BackoffConstant(20*time.Millisecond)), RetryMaxElapsed(50*time.Millisecond))
+        if err == nil || calls < 2 || calls > 3 {
+            t.Errorf("err=%v calls=%d", err, calls)
+        }
+    })
+
+    t.Run("单次超时", func(t *testing.T) {
+        err := RetryWithOptions(context.Background(), func(ctx context.Context) error {
+            <-ctx.Done()
+            return ctx.Err()
+        }, RetryAttempts(2), RetryBackoff(BackoffConstant(0)), RetryAttemptTimeout(10*time.Millisecond))
+        if !errors.Is(err, context.DeadlineExceeded) {
+            t.Errorf("err = %v", err)
+        }
+    })
+}
+
+func TestRetryPolicyException(t *testing.T) {
+    policy := RetryPolicyException()
+    tests := []struct {
+        name string
+        err  error
+        want bool
+    }{
+        {"网络错误", ErrExceptionNetwork, true},
+        {"超时", ErrExceptionTimeout, true},
+        {"请求过多", ErrExceptionTooManyRequests, true},
+        {"参数错误", ErrExceptionInvalidArgs, false},
+        {"未授权", ErrExceptionUnauthorized, false},
+        {"普通错误", errors.New("boom"), true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := policy(tt.err); got != tt.want {
+                t.Errorf("policy(%v) = %v, 期望 %v", tt.err, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestRetryClassification(t *testing.T) {
+    t.Run("内置永久错误不重试", func(t *testing.T) {
+        calls := 0
+        err := RetryWithOptions(context.Background(), func(ctx context.Context) error {
+            calls++
+            return ErrExceptionInvalidArgs
+        }, RetryBackoff(BackoffConstant(0)))
+        if err != ErrExceptionInvalidArgs || calls != 1 {
+            t.Errorf("err=%v calls=%d", err, calls)
+        }
+    })
+
+    t.Run("Permanent立即停止", func(t *testing.T) {
+        calls := 0
+        origin := errors.New("origin")
+        err := Retry(5, func() error {
+            calls++
+            return Permanent(origin)
+        })
+        if err != origin || calls != 1 {
+            t.Errorf("err=%v calls=%d", err, calls)
+        }
+    })
+
+    t.Run("Retry使用相同分类", func(t *testing.T) {
+        calls := 0
+        err := Retry(5, func() error {
+            calls++
+            return ErrExceptionInvalidArgs
+        })
+        if err != ErrExceptionInvalidArgs || calls != 1 {
+            t.Errorf("err=%v calls=%d", err, calls)
+        }
+    })
+
+    t.Run("RetryInterval遵守RetryAfter", func(t *testing.T) {
+        calls := 0
+        start := time.Now()
+        err := RetryInterval(3, time.Hour, func() error {
+            calls++
+            if calls == 1 {
+                return RetryAfter(ErrExceptionTooManyRequests, time.Millisecond)
+            }
+            return nil
+        })
+        if err != nil || calls != 2 || time.Since(start) > time.Second {
+            t.Errorf("err=%v calls=%d elapsed=%v", err, calls, time.Since(start))
+        }
+    })
+
+    t.Run("RetryAfter覆盖退避", func(t *testing.T) {
+        var delays []time.Duration
+        calls := 0
+        err := RetryWithOptions(context.Background(), func(ctx context.Context) error {
+            calls++
+            if calls == 1 {
+                return RetryAfter(ErrExceptionInvalidArgs, time.Millisecond)
+            }
+            return nil
+        }, RetryBackoff(BackoffConstant(time.Hour)), RetryOnRetry(func(attempt int, err error, next time.Duration) {
+            delays = append(delays, next)
+        }))
+        if err != nil || len(delays) != 1 || delays[0] != time.Millisecond {
+            t.Errorf("err=%v delays=%v", err, delays)
+        }
+    })
+}
@@ -0,0 +1,332 @@
+package rr
+
+import (
+    "context"
+    "math/rand/v2"
+    "sync"
+    "time"
+)
+
+var ErrSchedulerStopped = newBuiltinException("Scheduler is stopped", "SCHEDULER_STOPPED", 503, CanonicalUnavailable)
+
+type everySchedule struct {
+    d time.Duration
+}
+
+// 每隔 d 执行一次, 按计划时间计算, 不受执行耗时影响
+func Every(d time.Duration) Schedule {
+    return everySchedule{d: max(d, time.Millisecond)}
+}
+
+func (r everySchedule) Next(after time.Time) time.Time {
+    return after.Add(r.d)
+}
+
+type atSchedule struct {
+    t time.Time
+}
+
+// 在 t 执行一次
+func At(t time.Time) Schedule {
+    return atSchedule{t: t}
+}
+
+func (r atSchedule) Next(after time.Time) time.Time {
+    if r.t.After(after) {
+        return r.t
+    }
+    return time.Time{}
+}
+
+// 加入调度器 d 之后执行一次
+func After(d time.Duration) Schedule {
+    return afterSchedule{d: d}
+}
+
+type afterSchedule struct {
+    d time.Duration
+}
+
+// 相对时间在加入调度器时才确定, 直接调用 Next 时以 after 为起点
+func (r afterSchedule) Next(after time.Time) time.Time {
+    return after.Add(r.d)
+}
+
+// 上一次执行尚未结束时又到了执行时间的处理方式
+type OverlapPolicy int
+
+const (
+    // 跳过本次
+    OverlapSkip OverlapPolicy = iota
+    // 等上一次结束后执行, 积压的次数会依次执行
+    OverlapQueue
+    // 并发执行
+    OverlapAllow
+)
+
+type jobOptions struct {
+    overlap OverlapPolicy
+    jitter  time.Duration
+    onError func(error)
+}
+
+type JobOption func(*jobOptions)
+
+// 重叠策略, 默认 OverlapSkip
+func JobOverlap(p OverlapPolicy) JobOption {
+    return func(r *jobOptions) {
+        r.overlap = p
+    }
+}
+
+// 每次执行随机推迟 [0, d)
+func JobJitter(d time.Duration) JobOption {
+    return func(r *jobOptions) {
+        if d > 0 {
+            r.jitter = d
+        }
+    }
+}
+
+// 执行返回错误或 panic 时的回调
+func JobOnError(f func(error)) JobOption {
+    return func(r *jobOptions) {
+        r.onError = f
+    }
+}
+
+// 调度器, 每个任务使用一个 goroutine 等待执行时间
+type Scheduler interface {
+    // 按 s 执行 fn, ctx 结束时任务停止并取消正在执行的 fn
+    Schedule(ctx context.Context, s Schedule, fn func(ctx context.Context) error, opts ...JobOption) (Job, error)
+    // 停止所有任务并等待正在执行的 fn 结束, ctx 结束时返回 ctx.Err()
+    Stop(ctx context.Context) error
+}
+
+type Job interface {
+    // 停止任务并取消正在执行的 fn, 不等待其结束
+    Cancel()
+    // 任务停止且所有执行都已结束后关闭
+    Done() <-chan struct{}
+    // 下一次计划执行时间, 不含抖动; 没有下一次时返回零值
+    Next() time.Time
+    // 预览之后 n 次计划执行时间, 不含抖动
+    NextRuns(n int) []time.Time
+    // 已完成的执行次数与因重叠被跳过的次数
+    Runs() int
+    Skipped() int
+}
+
+type SchedulerOption func(*scheduler)
+
+// 使用的时钟, 默认 SystemClock
+func SchedulerClock(c Clock) SchedulerOption {
+    return func(r *scheduler) {
+        if c != nil {
+            r.clock = c
+        }
+    }
+}
+
+type scheduler struct {
+    clock   Clock
+    mu      sync.Mutex
+    jobs    map[*job]struct{}
+    stopped bool
+}
+
+func NewScheduler(opts ...SchedulerOption) Scheduler {
+    r := &scheduler{clock: SystemClock, jobs: make(map[*job]struct{})}
+    for _, opt := range opts {
+        opt(r)
+    }
+    return r
+}
+
+func (r *scheduler) Schedule(ctx context.Context, s Schedule, fn func(ctx context.Context) error, opts ...JobOption) (Job, error) {
+    now := r.clock.Now()
+    if a, ok := s.(afterSchedule); ok {
+        s = At(now.Add(a.d))
+    }
+    j := &job{
+        scheduler: r,
+        schedule:  s,
+        fn:        fn,
+        done:      make(chan struct{}),
+        next:      s.Next(now),
+    }
+    for _, opt := range opts {
+        opt(&j.opts)
+    }
+    j.ctx, j.cancel = context.WithCancel(ctx)
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if r.stopped {
+        j.cancel()
+        return nil, ErrSchedulerStopped
+    }
+    r.jobs[j] = struct{}{}
+    go j.loop()
+    return j, nil
+}
+
+func (r *scheduler) Stop(ctx context.Context) error {
+    r.mu.Lock()
+    r.stopped = true
+    jobs := make([]*job, 0, len(r.jobs))
+    for j := range r.jobs {
+        jobs = append(jobs, j)
+    }
+    r.mu.Unlock()
+    for _, j := range jobs {
+        j.Cancel()
+    }
+    for _, j := range jobs {
+        select {
+        case <-j.done:
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    }
+    return nil
+}
+
+type job struct {
+    scheduler *scheduler
+    schedule  Schedule
+    fn        func(ctx context.Context) error
+    opts      jobOptions
+    ctx       context.Context
+    cancel    context.CancelFunc
+    done      chan struct{}
+    wg        sync.WaitGroup
+
+    mu      sync.Mutex
+    next    time.Time
+    running int
+    pending int
+    runs    int
+    skipped int
+}
+
+func (j *job) loop() {
+    defer func() {
+        j.wg.Wait()
+        j.scheduler.mu.Lock()
+        delete(j.scheduler.jobs, j)
+        j.scheduler.mu.Unlock()
+        close(j.done)
+    }()
+    clock := j.scheduler.clock
+    for {
+        next := j.Next()
+        if next.IsZero() {
+            return
+        }
+        d := next.Sub(clock.Now())
+        if j.opts.jitter > 0 {
+            d += rand.N(j.opts.jitter)
+        }
+        t := clock.NewTimer(d)
+        select {
+        case <-t.C():
+        case <-j.ctx.Done():
+            t.Stop()
+            return
+        }
+        j.trigger()
+        // 错过的执行时间直接跳过
+        now := clock.Now()
+        next = j.schedule.Next(next)
+        if !next.IsZero() && next.Before(now) {
+            next = j.schedule.Next(now)
+        }
+        j.mu.Lock()
+        j.next = next
+        j.mu.Unlock()
+    }
+}
+
+func (j *job) trigger() {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+    if j.running > 0 {
+        switch j.opts.overlap {
+        case OverlapSkip:
+            j.skipped++
+            return
+        case OverlapQueue:
+            j.pending++
+            return
+        }
+    }
+    j.running++
+    j.wg.Add(1)
+    go j.run()
+}
+
+func (j *job) run() {
+    defer j.wg.Done()
+    for {
+        if err := j.call(); err != nil && j.opts.onError != nil {
+            j.opts.onError(err)
+        }
+        j.mu.Lock()
+        j.runs++
+        if j.pending > 0 && j.ctx.Err() == nil {
+            j.pending--
+            j.mu.Unlock()
+            continue
+        }
+        j.pending = 0
+        j.running--
+        j.mu.Unlock()
+        return
+    }
+}
+
+func (j *job) call() (err error) {
+    defer func() {
+        if r := recover(); r != nil {
+            err = NewPanicException("scheduled job panicked", r)
+        }
+    }()
+    return j.fn(j.ctx)
+}
+
+func (j *job) Cancel() {
+    j.cancel()
+}
+
+func (j *job) Done() <-chan struct{} {
+    return j.done
+}
+
+func (j *job) Next() time.Time {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+    if j.ctx.Err() != nil {
+        return time.Time{}
+    }
+    return j.next
+}
+
+func (j *job) NextRuns(n int) []time.Time {
+    var runs []time.Time
+    for t := j.Next(); !t.IsZero() && len(runs) < n; t = j.schedule.Next(t) {
+        runs = append(runs, t)
+    }
+    return runs
+}
+
+func (j *job) Runs() int {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+    return j.runs
+}
+
+func (j *job) Skipped() int {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+    return j.skipped
+}
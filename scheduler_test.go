@@ -0,0 +1,178 @@
+package rr
+
+import (
+    "context"
+    "errors"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// 等待任务 goroutine 设置好定时器后推进时钟
+func advanceScheduler(t *testing.T, c *ManualClock, d time.Duration) {
+    t.Helper()
+    deadline := time.Now().Add(time.Second)
+    for c.PendingTimers() == 0 {
+        if time.Now().After(deadline) {
+            t.Fatal("定时器未设置")
+        }
+        time.Sleep(time.Millisecond)
+    }
+    c.Advance(d)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+    t.Helper()
+    deadline := time.Now().Add(time.Second)
+    for !cond() {
+        if time.Now().After(deadline) {
+            t.Fatal("等待超时")
+        }
+        time.Sleep(time.Millisecond)
+    }
+}
+
+func TestSchedulerEvery(t *testing.T) {
+    start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    c := NewManualClock(start)
+    s := NewScheduler(SchedulerClock(c))
+    var runs atomic.Int32
+    job, err := s.Schedule(context.Background(), Every(time.Minute), func(ctx context.Context) error {
+        runs.Add(1)
+        return nil
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := []time.Time{start.Add(time.Minute), start.Add(2 * time.Minute), start.Add(3 * time.Minute)}
+    if got := job.NextRuns(3); len(got) != 3 || !got[0].Equal(want[0]) || !got[2].Equal(want[2]) {
+        t.Errorf("next runs = %v", got)
+    }
+    for i := 1; i <= 3; i++ {
+        advanceScheduler(t, c, time.Minute)
+        waitFor(t, func() bool { return job.Runs() == i })
+    }
+    if runs.Load() != 3 {
+        t.Errorf("runs = %d", runs.Load())
+    }
+
+    // 错过的执行时间被跳过
+    waitFor(t, func() bool { return job.Next().Equal(start.Add(4 * time.Minute)) })
+    advanceScheduler(t, c, 10*time.Minute)
+    waitFor(t, func() bool { return job.Runs() == 4 })
+    waitFor(t, func() bool { return job.Next().Equal(start.Add(14 * time.Minute)) })
+
+    if err := s.Stop(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    <-job.Done()
+    if !job.Next().IsZero() {
+        t.Error("停止后没有下一次")
+    }
+    if _, err := s.Schedule(context.Background(), Every(time.Second), func(ctx context.Context) error { return nil }); !errors.Is(err, ErrSchedulerStopped) {
+        t.Errorf("err = %v", err)
+    }
+}
+
+func TestSchedulerAtAfter(t *testing.T) {
+    start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    c := NewManualClock(start)
+    s := NewScheduler(SchedulerClock(c))
+    var errs atomic.Int32
+    var ran atomic.Bool
+    after, _ := s.Schedule(context.Background(), After(time.Hour), func(ctx context.Context) error {
+        panic("boom")
+    }, JobOnError(func(err error) {
+        if errors.Is(err, ErrExceptionPanic) {
+            errs.Add(1)
+        }
+    }))
+    at, _ := s.Schedule(context.Background(), At(start.Add(30*time.Minute)), func(ctx context.Context) error {
+        ran.Store(true)
+        return nil
+    })
+    if !after.Next().Equal(start.Add(time.Hour)) || len(at.NextRuns(5)) != 1 {
+        t.Fatalf("next = %v %v", after.Next(), at.NextRuns(5))
+    }
+    waitFor(t, func() bool { return c.PendingTimers() == 2 })
+    c.Advance(time.Hour)
+    <-at.Done()
+    <-after.Done()
+    if !ran.Load() || errs.Load() != 1 {
+        t.Errorf("ran=%v errs=%d", ran.Load(), errs.Load())
+    }
+
+    past, _ := s.Schedule(context.Background(), At(start), func(ctx context.Context) error { return nil })
+    <-past.Done()
+    if past.Runs() != 0 {
+        t.Error("过去的时间不应执行")
+    }
+}
+
+func TestSchedulerOverlap(t *testing.T) {
+    for _, c := range []struct {
+        policy  OverlapPolicy
+        runs    int
+        skipped int
+        peak    int32
+    }{
+        {OverlapSkip, 1, 2, 1},
+        {OverlapQueue, 3, 0, 1},
+        {OverlapAllow, 3, 0, 3},
+    } {
+        clock := NewManualClock(time.Now())
+        s := NewScheduler(SchedulerClock(clock))
+        release := make(chan struct{})
+        var running, peak atomic.Int32
+        var started atomic.Int32
+        job, _ := s.Schedule(context.Background(), Every(time.Second), func(ctx context.Context) error {
+            n := running.Add(1)
+            if n > peak.Load() {
+                peak.Store(n)
+            }
+            started.Add(1)
+            <-release
+            running.Add(-1)
+            return nil
+        }, JobOverlap(c.policy))
+        for i := 0; i < 3; i++ {
+            advanceScheduler(t, clock, time.Second)
+            // 等待本次触发被处理
+            waitFor(t, func() bool { return clock.PendingTimers() == 1 })
+        }
+        close(release)
+        waitFor(t, func() bool { return job.Runs() == c.runs })
+        // 取消后积压的执行会被丢弃
+        job.Cancel()
+        <-job.Done()
+        if job.Runs() != c.runs || job.Skipped() != c.skipped || peak.Load() != c.peak {
+            t.Errorf("policy %d: runs=%d skipped=%d peak=%d", c.policy, job.Runs(), job.Skipped(), peak.Load())
+        }
+        s.Stop(context.Background())
+    }
+}
+
+func TestSchedulerCancel(t *testing.T) {
+    c := NewManualClock(time.Now())
+    s := NewScheduler(SchedulerClock(c))
+    ctx, cancel := context.WithCancel(context.Background())
+    started := make(chan struct{})
+    job, _ := s.Schedule(ctx, Every(time.Second), func(ctx context.Context) error {
+        close(started)
+        <-ctx.Done()
+        return ctx.Err()
+    }, JobJitter(time.Millisecond))
+    waitFor(t, func() bool { return c.PendingTimers() == 1 })
+    c.Advance(2 * time.Second)
+    <-started
+    // 任务 ctx 结束时停止并取消正在执行的 fn
+    cancel()
+    select {
+    case <-job.Done():
+    case <-time.After(time.Second):
+        t.Fatal("任务未停止")
+    }
+    if job.Runs() != 1 {
+        t.Errorf("runs = %d", job.Runs())
+    }
+}
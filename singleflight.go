@@ -0,0 +1,114 @@
+package rr
+
+import (
+    "context"
+    "sync"
+)
+
+// 按 key 合并并发调用: 同一 key 同时只执行一次 fn, 其余调用方共享其结果
+// 零值可用
+type Group[K comparable, V any] struct {
+    mu    sync.Mutex
+    calls map[K]*groupCall[V]
+}
+
+// DoChan 的结果, Shared 表示结果被多个调用方共享
+type GroupResult[V any] struct {
+    Val    V
+    Err    error
+    Shared bool
+}
+
+type groupCall[V any] struct {
+    done   chan struct{}
+    val    V
+    err    error
+    dups   int
+    refs   int
+    cancel context.CancelFunc
+}
+
+// 执行或加入 key 对应的调用并等待结果
+// fn 的 ctx 继承首个调用方 ctx 的值但不继承取消; 调用方 ctx 结束时只有该调用方返回 ctx.Err(),
+// 所有调用方都离开后 fn 的 ctx 才会被取消; fn 中的 panic 转换为错误返回给每个调用方
+func (g *Group[K, V]) Do(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (V, error) {
+    r := <-g.DoChan(ctx, key, fn)
+    return r.Val, r.Err
+}
+
+// 与 Do 相同, 结果通过返回的通道送达, 通道只会收到一个结果
+func (g *Group[K, V]) DoChan(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) <-chan GroupResult[V] {
+    ch := make(chan GroupResult[V], 1)
+    c := g.join(ctx, key, fn)
+    select {
+    case <-c.done:
+        ch <- GroupResult[V]{Val: c.val, Err: c.err, Shared: c.dups > 0}
+        return ch
+    default:
+    }
+    go func() {
+        select {
+        case <-c.done:
+            ch <- GroupResult[V]{Val: c.val, Err: c.err, Shared: c.dups > 0}
+        case <-ctx.Done():
+            g.leave(key, c)
+            ch <- GroupResult[V]{Err: ctx.Err()}
+        }
+    }()
+    return ch
+}
+
+// 使 key 对应的调用不再被后来者加入, 已在等待的调用方仍会收到其结果
+func (g *Group[K, V]) Forget(key K) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    delete(g.calls, key)
+}
+
+func (g *Group[K, V]) join(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) *groupCall[V] {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.calls == nil {
+        g.calls = make(map[K]*groupCall[V])
+    }
+    if c, ok := g.calls[key]; ok {
+        c.dups++
+        c.refs++
+        return c
+    }
+    callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+    c := &groupCall[V]{done: make(chan struct{}), refs: 1, cancel: cancel}
+    g.calls[key] = c
+    go g.run(callCtx, key, c, fn)
+    return c
+}
+
+func (g *Group[K, V]) run(ctx context.Context, key K, c *groupCall[V], fn func(ctx context.Context) (V, error)) {
+    defer func() {
+        if r := recover(); r != nil {
+            c.err = NewPanicException("singleflight call panicked", r)
+        }
+        g.mu.Lock()
+        if g.calls[key] == c {
+            delete(g.calls, key)
+        }
+        g.mu.Unlock()
+        c.cancel()
+        close(c.done)
+    }()
+    c.val, c.err = fn(ctx)
+}
+
+// 调用方放弃等待, 最后一个调用方离开时取消 fn 的 ctx
+func (g *Group[K, V]) leave(key K, c *groupCall[V]) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    c.refs--
+    if c.refs > 0 {
+        return
+    }
+    if g.calls[key] == c {
+        delete(g.calls, key)
+    }
+    c.cancel()
+}
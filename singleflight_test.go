@@ -0,0 +1,136 @@
+package rr
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+func TestGroupDo(t *testing.T) {
+    var g Group[string, int]
+    var calls atomic.Int32
+    release := make(chan struct{})
+    var wg sync.WaitGroup
+    results := make([]GroupResult[int], 5)
+    for i := range results {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            results[i] = <-g.DoChan(context.Background(), "k", func(ctx context.Context) (int, error) {
+                calls.Add(1)
+                <-release
+                return 42, nil
+            })
+        }(i)
+    }
+    // 等待所有调用方加入
+    for {
+        g.mu.Lock()
+        c := g.calls["k"]
+        joined := c != nil && c.refs == 5
+        g.mu.Unlock()
+        if joined {
+            break
+        }
+        time.Sleep(time.Millisecond)
+    }
+    close(release)
+    wg.Wait()
+    if calls.Load() != 1 {
+        t.Errorf("calls = %d", calls.Load())
+    }
+    for _, r := range results {
+        if r.Val != 42 || r.Err != nil || !r.Shared {
+            t.Errorf("result = %+v", r)
+        }
+    }
+
+    v, err := g.Do(context.Background(), "k", func(ctx context.Context) (int, error) {
+        return 1, nil
+    })
+    if v != 1 || err != nil {
+        t.Errorf("调用结束后应重新执行, v=%v err=%v", v, err)
+    }
+
+    _, err = g.Do(context.Background(), "p", func(ctx context.Context) (int, error) {
+        panic("boom")
+    })
+    if !errors.Is(err, ErrExceptionPanic) {
+        t.Errorf("panic 应转换为错误, err = %v", err)
+    }
+}
+
+func TestGroupDetach(t *testing.T) {
+    var g Group[string, int]
+    release := make(chan struct{})
+    var fnCtx context.Context
+    started := make(chan struct{})
+    fn := func(ctx context.Context) (int, error) {
+        fnCtx = ctx
+        close(started)
+        select {
+        case <-release:
+            return 7, nil
+        case <-ctx.Done():
+            return 0, ctx.Err()
+        }
+    }
+    ctx, cancel := context.WithCancel(context.Background())
+    first := g.DoChan(ctx, "k", fn)
+    <-started
+    second := g.DoChan(context.Background(), "k", fn)
+
+    // 首个调用方离开后共享调用继续执行
+    cancel()
+    if r := <-first; !errors.Is(r.Err, context.Canceled) {
+        t.Errorf("first = %+v", r)
+    }
+    if fnCtx.Err() != nil {
+        t.Fatal("仍有调用方等待时不应取消")
+    }
+    close(release)
+    if r := <-second; r.Val != 7 || r.Err != nil {
+        t.Errorf("second = %+v", r)
+    }
+
+    // 所有调用方离开后取消共享调用
+    started = make(chan struct{})
+    ctx, cancel = context.WithCancel(context.Background())
+    only := g.DoChan(ctx, "x", func(ctx context.Context) (int, error) {
+        fnCtx = ctx
+        close(started)
+        <-ctx.Done()
+        return 0, ctx.Err()
+    })
+    <-started
+    cancel()
+    <-only
+    select {
+    case <-fnCtx.Done():
+    case <-time.After(time.Second):
+        t.Error("fn 的 ctx 应被取消")
+    }
+}
+
+func TestGroupForget(t *testing.T) {
+    var g Group[int, string]
+    release := make(chan struct{})
+    started := make(chan struct{})
+    old := g.DoChan(context.Background(), 1, func(ctx context.Context) (string, error) {
+        close(started)
+        <-release
+        return "old", nil
+    })
+    <-started
+    g.Forget(1)
+    v, _ := g.Do(context.Background(), 1, func(ctx context.Context) (string, error) {
+        return "new", nil
+    })
+    close(release)
+    if r := <-old; v != "new" || r.Val != "old" {
+        t.Errorf("v=%v old=%+v", v, r)
+    }
+}
@@ -2,7 +2,7 @@ package rr
 
 import (
     "context"
-    "fmt"
+    "errors"
     "sync"
     "sync/atomic"
     "time"
@@ -10,60 +10,161 @@ import (
 
 // 实现支持错误返回的once,并且执行失败的时候,第二次还会执行,直到成功
 type Once struct {
-    done uint32
-    m    sync.Mutex
+    v OnceValue[struct{}]
 }
 
 func (o *Once) Do(f func() error) error {
-    if atomic.LoadUint32(&o.done) == 1 {
-        return nil
-    }
-    return o.doSlow(f)
+    return o.DoCtx(context.Background(), f)
+}
+
+// 等待其它 goroutine 执行 f 期间 ctx 结束时返回 ctx.Err()
+func (o *Once) DoCtx(ctx context.Context, f func() error) error {
+    _, err := o.v.DoCtx(ctx, func() (struct{}, error) {
+        return struct{}{}, f()
+    })
+    return err
+}
+
+// 重置为未执行状态
+func (o *Once) Reset() {
+    o.v.Reset()
+}
+
+var ErrTaskCancelled = newBuiltinException("Task cancelled", "TASK_CANCELLED", 499, CanonicalCanceled)
+
+// 异步任务的公共部分: 每个任务持有从调用方 ctx 派生的独立 ctx, 可单独取消
+type taskCore struct {
+    ctx       context.Context
+    cancel    context.CancelCauseFunc
+    cancelled atomic.Bool
+    err       error
+    doneCh    chan struct{}
+    once      sync.Once
+    done      atomic.Bool
+    progress  taskProgress
+}
+
+// 任务 ctx 中注入进度上报器, fn 通过 ProgressFromContext 取得
+func (t *taskCore) init(ctx context.Context) {
+    ctx = context.WithValue(ctx, progressKey{}, &t.progress)
+    t.ctx, t.cancel = context.WithCancelCause(ctx)
+    t.doneCh = make(chan struct{})
 }
-func (o *Once) doSlow(callback func() error) error {
-    o.m.Lock()
-    defer o.m.Unlock()
+
+// 同步执行 fn, panic 转换为异常; 执行前已被取消则直接以 ErrTaskCancelled 结束
+func (t *taskCore) run(panicPrefix string, fn func(ctx context.Context) error) {
     var err error
-    if o.done == 0 {
-        err = callback()
-        if err == nil {
-            atomic.StoreUint32(&o.done, 1)
+    defer func() {
+        if r := recover(); r != nil {
+            err = NewPanicException(panicPrefix, r)
         }
+        t.finish(err)
+    }()
+    if t.cancelled.Load() {
+        err = ErrTaskCancelled
+        return
     }
-    return err
+    t.progress.started()
+    err = fn(t.ctx)
+}
+
+// 记录结果并通知完成, 通过 Cancel 取消后返回的错误会被包装为 ErrTaskCancelled
+// 先取消 ctx 再关闭 doneCh, 等待方返回时 Context() 一定已结束
+func (t *taskCore) finish(err error) {
+    t.once.Do(func() {
+        // Cancel 先取消 ctx 再设置标记, 两者之间结束的任务通过 cause 识别
+        cancelled := t.cancelled.Load() || errors.Is(context.Cause(t.ctx), ErrTaskCancelled)
+        if err != nil && cancelled && !errors.Is(err, ErrTaskCancelled) {
+            err = ErrTaskCancelled.With(err)
+        }
+        t.err = err
+        t.progress.finish()
+        if cancelled {
+            t.cancel(ErrTaskCancelled)
+        } else {
+            t.cancel(nil)
+        }
+        t.done.Store(true)
+        close(t.doneCh)
+    })
+}
+
+// 取消任务的 ctx, 不等待任务结束
+func (t *taskCore) Cancel() {
+    t.cancel(ErrTaskCancelled)
+    t.cancelled.Store(true)
+}
+
+// 任务自己的 ctx, 任务结束或被取消后 Done
+func (t *taskCore) Context() context.Context {
+    return t.ctx
+}
+
+// 等待任务完成并返回其错误, ctx 先结束时返回 ctx.Err(), 不影响任务继续运行
+func (t *taskCore) Wait(ctx context.Context) error {
+    select {
+    case <-t.doneCh:
+        return t.err
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// 检查任务是否已完成（非阻塞）
+func (t *taskCore) IsDone() bool {
+    return t.done.Load()
+}
+
+func (t *taskCore) Done() <-chan struct{} {
+    return t.doneCh
+}
+
+// 最新的进度
+func (t *taskCore) Progress() Progress {
+    return t.progress.snapshot()
+}
+
+// 订阅进度更新, 通道只保留最新的一条, 任务结束或调用返回的函数取消订阅后关闭
+func (t *taskCore) SubscribeProgress() (<-chan Progress, func()) {
+    return t.progress.subscribe()
+}
+
+func (t *taskCore) Timing() TaskTiming {
+    return t.progress.timing()
 }
 
 type async struct {
-    err    error
-    doneCh chan struct{}
-    once   sync.Once
-    done   atomic.Bool
+    taskCore
 }
 type AsyncTask interface {
     Get() error
     IsDone() bool
     Done() <-chan struct{}
     HeartbeatWait(c context.Context, interval time.Duration, onHeartbeat func()) error
+    // 取消任务, 任务返回的错误会被包装为 ErrTaskCancelled
+    Cancel()
+    // 任务自己的 ctx
+    Context() context.Context
+    // 等待完成, ctx 结束时提前返回
+    Wait(ctx context.Context) error
+    // 最新的进度, 任务通过 ProgressFromContext 上报
+    Progress() Progress
+    // 订阅进度更新, 返回的函数用于取消订阅; 任务结束后通道关闭
+    SubscribeProgress() (<-chan Progress, func())
+    // 开始/结束时间与耗时
+    Timing() TaskTiming
 }
 
 // 启动一个异步任务
+// fn 收到的 ctx 派生自调用方 ctx, 可通过 Cancel() 单独取消, 任务结束后也会被取消
 func Async(ctx context.Context, fn func(ctx context.Context) error) AsyncTask {
-    task := &async{doneCh: make(chan struct{})}
-
-    go func() {
-        defer func() {
-            if r := recover(); r != nil {
-                task.err = fmt.Errorf("async task panicked: %v", r)
-            }
-            task.once.Do(func() {
-                task.done.Store(true)
-                close(task.doneCh)
-            })
-        }()
-
-        task.err = fn(ctx)
-    }()
-
+    task := newAsync(ctx)
+    go task.run("async task panicked", fn)
+    return task
+}
+func newAsync(ctx context.Context) *async {
+    task := &async{}
+    task.init(ctx)
     return task
 }
 func (t *async) HeartbeatWait(c context.Context, interval time.Duration, onHeartbeat func()) error {
@@ -90,13 +191,13 @@ func (t *async) Get() error {
     return t.err
 }
 
-// 检查任务是否已完成（非阻塞）
-func (t *async) IsDone() bool {
-    return t.done.Load()
-}
-
-func (t *async) Done() <-chan struct{} {
-    return t.doneCh
+// 等待所有任务完成, 返回聚合后的 *Errors 或 nil
+func AsyncWaitAll(tasks ...AsyncTask) error {
+    var errs Errors
+    for _, t := range tasks {
+        errs.Append(t.Get())
+    }
+    return errs.ErrorOrNil()
 }
 
 // -------------------- AsyncResult[T] --------------------
@@ -107,11 +208,8 @@ func (t *async) Done() <-chan struct{} {
 //   v, err := res.Get()
 
 type asyncResult[T any] struct {
-    val    T
-    err    error
-    doneCh chan struct{}
-    once   sync.Once
-    done   atomic.Bool
+    taskCore
+    val T
 }
 
 // 泛型版本的任务接口
@@ -122,38 +220,41 @@ type AsyncResultTask[T any] interface {
     IsDone() bool
     // Done 返回完成通知通道
     Done() <-chan struct{}
+    // Cancel 取消任务, 任务返回的错误会被包装为 ErrTaskCancelled
+    Cancel()
+    // Context 任务自己的 ctx
+    Context() context.Context
+    // Wait 等待完成, ctx 结束时提前返回
+    Wait(ctx context.Context) error
+    // Progress 最新的进度, 任务通过 ProgressFromContext 上报
+    Progress() Progress
+    // SubscribeProgress 订阅进度更新, 返回的函数用于取消订阅; 任务结束后通道关闭
+    SubscribeProgress() (<-chan Progress, func())
+    // Timing 开始/结束时间与耗时
+    Timing() TaskTiming
 }
 
 // AsyncResult 启动一个带返回值的异步任务
 func AsyncResult[T any](ctx context.Context, fn func(ctx context.Context) (T, error)) AsyncResultTask[T] {
-    task := &asyncResult[T]{doneCh: make(chan struct{})}
-
-    go func() {
-        defer func() {
-            if r := recover(); r != nil {
-                task.err = fmt.Errorf("async result task panicked: %v", r)
-            }
-            task.once.Do(func() {
-                task.done.Store(true)
-                close(task.doneCh)
-            })
-        }()
-
-        v, err := fn(ctx)
-        task.val, task.err = v, err
-    }()
-
+    task := newAsyncResult[T](ctx)
+    go task.runResult("async result task panicked", fn)
+    return task
+}
+func newAsyncResult[T any](ctx context.Context) *asyncResult[T] {
+    task := &asyncResult[T]{}
+    task.init(ctx)
     return task
 }
+func (t *asyncResult[T]) runResult(panicPrefix string, fn func(ctx context.Context) (T, error)) {
+    t.run(panicPrefix, func(ctx context.Context) error {
+        v, err := fn(ctx)
+        t.val = v
+        return err
+    })
+}
 
 // Get 阻塞等待并返回结果
 func (t *asyncResult[T]) Get() (T, error) {
     <-t.doneCh
     return t.val, t.err
 }
-
-// IsDone 非阻塞检查
-func (t *asyncResult[T]) IsDone() bool { return t.done.Load() }
-
-// Done 完成通知
-func (t *asyncResult[T]) Done() <-chan struct{} { return t.doneCh }
@@ -0,0 +1,77 @@
+package rr
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+)
+
+func TestAsyncCancel(t *testing.T) {
+    task := Async(context.Background(), func(ctx context.Context) error {
+        <-ctx.Done()
+        return ctx.Err()
+    })
+    task.Cancel()
+    err := task.Get()
+    if !errors.Is(err, ErrTaskCancelled) {
+        t.Fatalf("err = %v", err)
+    }
+    if !errors.Is(context.Cause(task.Context()), ErrTaskCancelled) {
+        t.Errorf("Context() 的 cause = %v", context.Cause(task.Context()))
+    }
+
+    // 取消一个任务不影响同一父 ctx 下的其它任务
+    parent := context.Background()
+    a := AsyncResult(parent, func(ctx context.Context) (int, error) {
+        <-ctx.Done()
+        return 0, ctx.Err()
+    })
+    b := AsyncResult(parent, func(ctx context.Context) (int, error) {
+        return 2, ctx.Err()
+    })
+    a.Cancel()
+    if _, err := a.Get(); !errors.Is(err, ErrTaskCancelled) {
+        t.Errorf("a err = %v", err)
+    }
+    if v, err := b.Get(); v != 2 || err != nil {
+        t.Errorf("b = %v, %v", v, err)
+    }
+}
+
+func TestAsyncFailureNotCancelled(t *testing.T) {
+    fail := errors.New("fail")
+    task := Async(context.Background(), func(ctx context.Context) error {
+        return fail
+    })
+    if err := task.Get(); err != fail {
+        t.Errorf("普通失败不应包装为取消, err = %v", err)
+    }
+    if task.Context().Err() == nil {
+        t.Errorf("任务结束后 Context 应被取消")
+    }
+    task.Cancel()
+    if err := task.Get(); err != fail {
+        t.Errorf("结束后取消不应改变结果, err = %v", err)
+    }
+}
+
+func TestAsyncWait(t *testing.T) {
+    release := make(chan struct{})
+    task := Async(context.Background(), func(ctx context.Context) error {
+        <-release
+        return nil
+    })
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    defer cancel()
+    if err := task.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("Wait 应在 ctx 超时后返回, err = %v", err)
+    }
+    if task.IsDone() {
+        t.Errorf("Wait 提前返回不应影响任务")
+    }
+    close(release)
+    if err := task.Wait(context.Background()); err != nil || !task.IsDone() {
+        t.Errorf("err = %v", err)
+    }
+}
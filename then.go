@@ -0,0 +1,51 @@
+package rr
+
+import (
+    "context"
+)
+
+// 源任务成功后以其结果执行 fn, 源任务失败时直接传递错误且不调用 fn
+// 不阻塞调用方; fn 中的 panic 与 AsyncResult 一样转换为错误
+func Then[T, U any](task AsyncResultTask[T], fn func(T) (U, error)) AsyncResultTask[U] {
+    return chain(task, func(v T, err error) (U, error) {
+        if err != nil {
+            var zero U
+            return zero, err
+        }
+        return fn(v)
+    })
+}
+
+// 源任务失败时调用 fn 尝试恢复, 成功时原样传递结果
+func Recover[T any](task AsyncResultTask[T], fn func(error) (T, error)) AsyncResultTask[T] {
+    return chain(task, func(v T, err error) (T, error) {
+        if err == nil {
+            return v, nil
+        }
+        return fn(err)
+    })
+}
+
+// 源任务结束后总会调用 fn, 然后原样传递结果; fn panic 时以 panic 错误结束
+func Finally[T any](task AsyncResultTask[T], fn func()) AsyncResultTask[T] {
+    return chain(task, func(v T, err error) (T, error) {
+        fn()
+        return v, err
+    })
+}
+
+// 等待源任务结束后执行 fn, 返回的新任务有自己的 ctx(继承源任务 ctx 的值, 不继承取消)
+// 取消新任务不会取消源任务; 源任务被取消时其 ErrTaskCancelled 会沿链传递
+func chain[T, U any](task AsyncResultTask[T], fn func(T, error) (U, error)) AsyncResultTask[U] {
+    next := newAsyncResult[U](context.WithoutCancel(task.Context()))
+    go next.runResult("async result task panicked", func(ctx context.Context) (U, error) {
+        select {
+        case <-task.Done():
+        case <-ctx.Done():
+            var zero U
+            return zero, ctx.Err()
+        }
+        return fn(task.Get())
+    })
+    return next
+}
@@ -0,0 +1,86 @@
+package rr
+
+import (
+    "errors"
+    "strconv"
+    "testing"
+    "time"
+)
+
+func TestThen(t *testing.T) {
+    src := delayed(10*time.Millisecond, 21, nil)
+    doubled := Then(src, func(v int) (int, error) {
+        return v * 2, nil
+    })
+    text := Then(doubled, func(v int) (string, error) {
+        return "v=" + strconv.Itoa(v), nil
+    })
+    if v, err := text.Get(); v != "v=42" || err != nil {
+        t.Fatalf("v=%q err=%v", v, err)
+    }
+
+    fail := errors.New("fail")
+    called := false
+    failed := Then(delayed(0, 1, fail), func(v int) (int, error) {
+        called = true
+        return v, nil
+    })
+    if _, err := failed.Get(); err != fail || called {
+        t.Errorf("源任务失败时不应调用 fn, err=%v called=%v", err, called)
+    }
+
+    panicked := Then(delayed(0, 1, nil), func(v int) (int, error) {
+        panic("boom")
+    })
+    if _, err := panicked.Get(); !errors.Is(err, ErrExceptionPanic) {
+        t.Errorf("panic 应转换为错误, err = %v", err)
+    }
+}
+
+func TestRecoverFinally(t *testing.T) {
+    fail := errors.New("fail")
+    finally := 0
+    task := Finally(Recover(delayed(0, 0, fail), func(err error) (int, error) {
+        if err != fail {
+            t.Errorf("Recover 收到 %v", err)
+        }
+        return 7, nil
+    }), func() {
+        finally++
+    })
+    if v, err := task.Get(); v != 7 || err != nil || finally != 1 {
+        t.Fatalf("v=%v err=%v finally=%d", v, err, finally)
+    }
+
+    kept := Recover(delayed(0, 3, nil), func(err error) (int, error) {
+        return 0, err
+    })
+    if v, err := kept.Get(); v != 3 || err != nil {
+        t.Errorf("成功时应原样传递, v=%v err=%v", v, err)
+    }
+}
+
+func TestThenCancel(t *testing.T) {
+    src := delayed(time.Hour, 1, nil)
+    next := Then(src, func(v int) (int, error) {
+        return v, nil
+    })
+    // 源任务被取消时错误沿链传递
+    src.Cancel()
+    if _, err := next.Get(); !errors.Is(err, ErrTaskCancelled) {
+        t.Errorf("err = %v", err)
+    }
+
+    src = delayed(time.Hour, 1, nil)
+    next = Then(src, func(v int) (int, error) {
+        return v, nil
+    })
+    next.Cancel()
+    if _, err := next.Get(); !errors.Is(err, ErrTaskCancelled) {
+        t.Errorf("err = %v", err)
+    }
+    if src.IsDone() {
+        t.Errorf("取消下游不应取消源任务")
+    }
+    src.Cancel()
+}
@@ -0,0 +1,178 @@
+package rr
+
+import (
+    "context"
+)
+
+type whenOptions struct {
+    cancelRemaining bool
+}
+
+type WhenOption func(*whenOptions)
+
+// 结果确定后取消其余未完成的任务
+func WhenCancelRemaining() WhenOption {
+    return func(r *whenOptions) {
+        r.cancelRemaining = true
+    }
+}
+
+// 带选项的任务组合, 通过 When 创建
+type WhenGroup[T any] struct {
+    ctx  context.Context
+    opts whenOptions
+}
+
+// 按 opts 组合等待任务, 例如 When[int](ctx, WhenCancelRemaining()).All(tasks...)
+func When[T any](ctx context.Context, opts ...WhenOption) *WhenGroup[T] {
+    r := &WhenGroup[T]{ctx: ctx}
+    for _, opt := range opts {
+        opt(&r.opts)
+    }
+    return r
+}
+
+// 单个任务的最终结果
+type Settled[T any] struct {
+    Value T
+    Err   error
+}
+
+// 等待所有任务成功, 按任务顺序返回结果; 任一失败或 ctx 结束时立即返回该错误
+func WhenAll[T any](ctx context.Context, tasks ...AsyncResultTask[T]) ([]T, error) {
+    return When[T](ctx).All(tasks...)
+}
+
+// 同 WhenAll
+func (w *WhenGroup[T]) All(tasks ...AsyncResultTask[T]) ([]T, error) {
+    stop := make(chan struct{})
+    defer close(stop)
+    defer w.cancel(tasks)
+    values := make([]T, len(tasks))
+    completed := whenWatch(tasks, stop)
+    for range tasks {
+        select {
+        case <-w.ctx.Done():
+            return nil, w.ctx.Err()
+        case i := <-completed:
+            v, err := tasks[i].Get()
+            if err != nil {
+                return nil, err
+            }
+            values[i] = v
+        }
+    }
+    return values, nil
+}
+
+// 返回最先完成的任务的结果, 不论成功或失败
+func WhenAny[T any](ctx context.Context, tasks ...AsyncResultTask[T]) (T, error) {
+    return When[T](ctx).Any(tasks...)
+}
+
+// 同 WhenAny
+func (w *WhenGroup[T]) Any(tasks ...AsyncResultTask[T]) (T, error) {
+    var zero T
+    if len(tasks) == 0 {
+        return zero, ErrExceptionInvalidArgs.WithT("WhenAny: no tasks")
+    }
+    stop := make(chan struct{})
+    defer close(stop)
+    defer w.cancel(tasks)
+    select {
+    case <-w.ctx.Done():
+        return zero, w.ctx.Err()
+    case i := <-whenWatch(tasks, stop):
+        return tasks[i].Get()
+    }
+}
+
+// 返回最先成功的任务的结果, 全部失败时返回按任务顺序聚合的 *Errors
+func WhenFirstSuccess[T any](ctx context.Context, tasks ...AsyncResultTask[T]) (T, error) {
+    return When[T](ctx).FirstSuccess(tasks...)
+}
+
+// 同 WhenFirstSuccess
+func (w *WhenGroup[T]) FirstSuccess(tasks ...AsyncResultTask[T]) (T, error) {
+    var zero T
+    if len(tasks) == 0 {
+        return zero, ErrExceptionInvalidArgs.WithT("WhenFirstSuccess: no tasks")
+    }
+    stop := make(chan struct{})
+    defer close(stop)
+    defer w.cancel(tasks)
+    errs := make([]error, len(tasks))
+    completed := whenWatch(tasks, stop)
+    for range tasks {
+        select {
+        case <-w.ctx.Done():
+            return zero, w.ctx.Err()
+        case i := <-completed:
+            v, err := tasks[i].Get()
+            if err == nil {
+                return v, nil
+            }
+            errs[i] = err
+        }
+    }
+    return zero, new(Errors).Append(errs...)
+}
+
+// 等待所有任务结束, 按任务顺序返回每个任务的值与错误
+// ctx 先结束时返回 ctx.Err(), 未完成的任务对应的 Err 为 ctx.Err()
+func WhenAllSettled[T any](ctx context.Context, tasks ...AsyncResultTask[T]) ([]Settled[T], error) {
+    return When[T](ctx).AllSettled(tasks...)
+}
+
+// 同 WhenAllSettled
+func (w *WhenGroup[T]) AllSettled(tasks ...AsyncResultTask[T]) ([]Settled[T], error) {
+    stop := make(chan struct{})
+    defer close(stop)
+    results := make([]Settled[T], len(tasks))
+    finished := make([]bool, len(tasks))
+    completed := whenWatch(tasks, stop)
+    for range tasks {
+        select {
+        case <-w.ctx.Done():
+            w.cancel(tasks)
+            for i := range results {
+                if !finished[i] {
+                    results[i].Err = w.ctx.Err()
+                }
+            }
+            return results, w.ctx.Err()
+        case i := <-completed:
+            v, err := tasks[i].Get()
+            results[i] = Settled[T]{Value: v, Err: err}
+            finished[i] = true
+        }
+    }
+    return results, nil
+}
+
+// 每个任务完成时把下标写入返回的通道, stop 关闭后停止等待
+func whenWatch[T any](tasks []AsyncResultTask[T], stop <-chan struct{}) <-chan int {
+    completed := make(chan int, len(tasks))
+    for i, t := range tasks {
+        go func(i int, t AsyncResultTask[T]) {
+            select {
+            case <-t.Done():
+                completed <- i
+            case <-stop:
+            }
+        }(i, t)
+    }
+    return completed
+}
+
+// 设置了 WhenCancelRemaining 时取消所有未完成的任务
+func (w *WhenGroup[T]) cancel(tasks []AsyncResultTask[T]) {
+    if !w.opts.cancelRemaining {
+        return
+    }
+    for _, t := range tasks {
+        if !t.IsDone() {
+            t.Cancel()
+        }
+    }
+}
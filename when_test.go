@@ -0,0 +1,95 @@
+package rr
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+)
+
+func delayed[T any](d time.Duration, v T, err error) AsyncResultTask[T] {
+    return AsyncResult(context.Background(), func(ctx context.Context) (T, error) {
+        select {
+        case <-time.After(d):
+            return v, err
+        case <-ctx.Done():
+            var zero T
+            return zero, ctx.Err()
+        }
+    })
+}
+
+func TestWhenAll(t *testing.T) {
+    values, err := WhenAll(context.Background(), delayed(30*time.Millisecond, 1, nil), delayed(0, 2, nil), delayed(10*time.Millisecond, 3, nil))
+    if err != nil || len(values) != 3 || values[0] != 1 || values[1] != 2 || values[2] != 3 {
+        t.Fatalf("values=%v err=%v", values, err)
+    }
+
+    fail := errors.New("fail")
+    slow := delayed(time.Hour, 0, nil)
+    _, err = When[int](context.Background(), WhenCancelRemaining()).All(slow, delayed(0, 0, fail))
+    if err != fail {
+        t.Fatalf("err = %v", err)
+    }
+    if _, err := slow.Get(); !errors.Is(err, ErrTaskCancelled) {
+        t.Errorf("失败后应取消其余任务, err = %v", err)
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    defer cancel()
+    other := delayed(time.Hour, 0, nil)
+    if _, err := WhenAll(ctx, other); !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("err = %v", err)
+    }
+    if other.IsDone() {
+        t.Errorf("未设置 WhenCancelRemaining 时不应取消任务")
+    }
+    other.Cancel()
+}
+
+func TestWhenAny(t *testing.T) {
+    fail := errors.New("fail")
+    slow := delayed(time.Hour, 1, nil)
+    v, err := When[int](context.Background(), WhenCancelRemaining()).Any(slow, delayed(0, 2, fail))
+    if v != 2 || err != fail {
+        t.Fatalf("v=%v err=%v", v, err)
+    }
+    if _, err := slow.Get(); !errors.Is(err, ErrTaskCancelled) {
+        t.Errorf("err = %v", err)
+    }
+    if _, err := WhenAny[int](context.Background()); !errors.Is(err, ErrExceptionInvalidArgs) {
+        t.Errorf("空任务 err = %v", err)
+    }
+}
+
+func TestWhenFirstSuccess(t *testing.T) {
+    e1, e2 := errors.New("e1"), errors.New("e2")
+    v, err := WhenFirstSuccess(context.Background(), delayed(0, 1, e1), delayed(20*time.Millisecond, 2, nil), delayed(0, 3, e2))
+    if v != 2 || err != nil {
+        t.Fatalf("v=%v err=%v", v, err)
+    }
+    _, err = WhenFirstSuccess(context.Background(), delayed(10*time.Millisecond, 1, e1), delayed(0, 2, e2))
+    var errs *Errors
+    if !errors.As(err, &errs) || errs.Len() != 2 || errs.Errors()[0] != e1 {
+        t.Errorf("全部失败 err = %v", err)
+    }
+}
+
+func TestWhenAllSettled(t *testing.T) {
+    fail := errors.New("fail")
+    results, err := WhenAllSettled(context.Background(), delayed(10*time.Millisecond, 1, nil), delayed(0, 0, fail))
+    if err != nil || results[0].Value != 1 || results[0].Err != nil || results[1].Err != fail {
+        t.Fatalf("results=%+v err=%v", results, err)
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    defer cancel()
+    slow := delayed(time.Hour, 0, nil)
+    results, err = When[int](ctx, WhenCancelRemaining()).AllSettled(delayed(0, 1, nil), slow)
+    if !errors.Is(err, context.DeadlineExceeded) || results[0].Value != 1 || !errors.Is(results[1].Err, context.DeadlineExceeded) {
+        t.Errorf("results=%+v err=%v", results, err)
+    }
+    if _, err := slow.Get(); !errors.Is(err, ErrTaskCancelled) {
+        t.Errorf("err = %v", err)
+    }
+}